@@ -17,9 +17,28 @@ func main() {
 	globalFlags := cli.PersistentFlags()
 	globalFlags.StringP("query", "q", "", "Start the fzf with this query")
 
+	cli.AddCommand(command.NewAuthorsSubcommand())
+	cli.AddCommand(command.NewBisectSubcommand())
+	cli.AddCommand(command.NewBlameSubcommand())
+	cli.AddCommand(command.NewCherryPickSubcommand())
+	cli.AddCommand(command.NewCleanSubcommand())
+	cli.AddCommand(command.NewCompareSubcommand())
+	cli.AddCommand(command.NewConfigSubcommand())
+	cli.AddCommand(command.NewConflictsSubcommand())
 	cli.AddCommand(command.NewDiffSubcommand())
+	cli.AddCommand(command.NewFilesSubcommand())
+	cli.AddCommand(command.NewFixupSubcommand())
+	cli.AddCommand(command.NewGrepSubcommand())
+	cli.AddCommand(command.NewIgnoredSubcommand())
 	cli.AddCommand(command.NewLogSubcommand())
+	cli.AddCommand(command.NewNotesSubcommand())
+	cli.AddCommand(command.NewRebaseTodoSubcommand())
+	cli.AddCommand(command.NewRefSubcommand())
+	cli.AddCommand(command.NewRestoreSubcommand())
+	cli.AddCommand(command.NewSparseSubcommand())
 	cli.AddCommand(command.NewStashSubcommand())
+	cli.AddCommand(command.NewSubmoduleSubcommand())
+	cli.AddCommand(command.NewWorktreeSubcommand())
 	if err := cli.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
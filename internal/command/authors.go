@@ -0,0 +1,216 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type authorsCli struct {
+	revision  string
+	fzfOption string
+}
+
+// authorStat is the statistics of commits by an author
+type authorStat struct {
+	firstTime time.Time
+	lastTime  time.Time
+	files     map[string]int
+}
+
+const (
+	// Both fields of the list and authors in the history are mapped by .mailmap.
+	// This isn't a template because < and > are escaped by html/template.
+	authorsFzfPreviewCommand = "git log --color --oneline --use-mailmap --fixed-strings --author=\\<{1}\\> %s"
+
+	authorsDateFormat   = "2006-01-02"
+	authorsTopFileCount = 3
+)
+
+func NewAuthorsSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "authors [<commit>]",
+		Short: "Select authors with fzf and see their commits with git fzf log",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			revision := "HEAD"
+			if len(args) > 0 {
+				revision = args[0]
+			}
+			cli, err := newAuthorsCli(revision, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newAuthorsCli(revision string, fzfQuery string) (*authorsCli, error) {
+	previewCommand := fmt.Sprintf(authorsFzfPreviewCommand, revision)
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --delimiter '\\t' --with-nth 2.."
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &authorsCli{
+		revision:  revision,
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c authorsCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	list, err := listAuthors(ctx, c.revision)
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("fzf %s", c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+
+	// Commits by any of the selected authors are shown
+	listOptions := []string{c.revision, "--use-mailmap", "--fixed-strings"}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		email := strings.SplitN(line, "\t", 2)[0]
+		if email == "" {
+			continue
+		}
+		listOptions = append(listOptions, "--author="+shellQuote("<"+email+">"))
+	}
+	if len(listOptions) == 3 {
+		return nil
+	}
+
+	// The revision is only for the list, because the preview shows the revision with a selected commit
+	cli, err := newLogCli([]string{}, "")
+	if err != nil {
+		return err
+	}
+	cli.listOptions = listOptions
+	return cli.Run(ctx, ioIn, ioOut, ioErr)
+}
+
+// listAuthors lists authors in the order of the number of commits like
+// <email><tab><commits><tab><name> <email><tab><first date> - <last date><tab><top files>
+func listAuthors(ctx context.Context, revision string) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, "shortlog", "-sne", revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git shortlog: %w", err)
+	}
+	stats, err := authorStats(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var list bytes.Buffer
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Each line is like <commits><tab><name> <<email>>
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		author := fields[1]
+		email := ""
+		if start := strings.LastIndex(author, "<"); start >= 0 {
+			email = strings.TrimSuffix(author[start+1:], ">")
+		}
+		fmt.Fprintf(&list, "%s\t%6s\t%s", email, fields[0], author)
+		if stat, ok := stats[author]; ok {
+			fmt.Fprintf(&list, "\t%s - %s\t%s", stat.firstTime.Format(authorsDateFormat), stat.lastTime.Format(authorsDateFormat), stat.topFiles(authorsTopFileCount))
+		}
+		list.WriteString("\n")
+	}
+	return list.Bytes(), nil
+}
+
+// authorStats collects the statistics of each author like <name> <<email>> from one git log
+func authorStats(ctx context.Context, revision string) (map[string]*authorStat, error) {
+	out, err := runGitCommandOutput(ctx, "log", "--use-mailmap", "--format=%x00%aN <%aE>%x09%at", "--name-only", revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git log: %w", err)
+	}
+
+	stats := map[string]*authorStat{}
+	for _, commit := range strings.Split(string(out), "\x00") {
+		lines := strings.Split(strings.TrimSpace(commit), "\n")
+		fields := strings.SplitN(lines[0], "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp of a commit: %s", fields[1])
+		}
+		commitTime := time.Unix(timestamp, 0)
+
+		stat, ok := stats[fields[0]]
+		if !ok {
+			stat = &authorStat{
+				firstTime: commitTime,
+				lastTime:  commitTime,
+				files:     map[string]int{},
+			}
+			stats[fields[0]] = stat
+		}
+		if commitTime.Before(stat.firstTime) {
+			stat.firstTime = commitTime
+		}
+		if commitTime.After(stat.lastTime) {
+			stat.lastTime = commitTime
+		}
+		for _, file := range lines[1:] {
+			if file != "" {
+				stat.files[file]++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// topFiles returns files changed most often by an author, and files are sorted by their names if the numbers are the same
+func (s authorStat) topFiles(count int) string {
+	files := make([]string, 0, len(s.files))
+	for file := range s.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if s.files[files[i]] != s.files[files[j]] {
+			return s.files[files[i]] > s.files[files[j]]
+		}
+		return files[i] < files[j]
+	})
+	if len(files) > count {
+		files = files[:count]
+	}
+	for i, file := range files {
+		files[i] = fmt.Sprintf("%s (%d)", file, s.files[file])
+	}
+	return strings.Join(files, ", ")
+}
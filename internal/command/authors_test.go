@@ -0,0 +1,185 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuthorsSubcommand(t *testing.T) {
+	assert.NotNil(t, NewAuthorsSubcommand())
+}
+
+func TestNewAuthorsCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		revision string
+		fzfQuery string
+		envVars  map[string]string
+		want     *authorsCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			revision: "HEAD",
+			fzfQuery: "",
+			want: &authorsCli{
+				revision:  "HEAD",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2..", "git log --color --oneline --use-mailmap --fixed-strings --author=\\<{1}\\> HEAD", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			revision: "origin/master",
+			fzfQuery: "john",
+			want: &authorsCli{
+				revision:  "origin/master",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --query john", "git log --color --oneline --use-mailmap --fixed-strings --author=\\<{1}\\> origin/master", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			revision: "HEAD",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newAuthorsCli(tc.revision, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestAuthorsCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	shortlog := "    10\tJohn Doe <john@example.com>\n     2\tJane Doe <jane@example.com>\n"
+	firstTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local).Unix()
+	lastTime := time.Date(2020, 3, 4, 5, 6, 7, 0, time.Local).Unix()
+	log := fmt.Sprintf("\x00John Doe <john@example.com>\t%d\n\nmain.go\nREADME.md\n\x00John Doe <john@example.com>\t%d\n\nmain.go\n", lastTime, firstTime)
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		wantErr         error
+		wantFzfCommands []string
+		wantIO          string
+	}{
+		{
+			name: "select authors",
+			fzfOutputs: []string{
+				"john@example.com\t    10\tJohn Doe <john@example.com>\njane@example.com\t     2\tJane Doe <jane@example.com>\n",
+				"abc Fix bug\n",
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				"fzf " + fzfOption,
+				fmt.Sprintf("git log --color --oneline origin/master --use-mailmap --fixed-strings --author='<john@example.com>' --author='<jane@example.com>' | fzf --multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantIO: "abc\n",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+			wantFzfCommands: []string{
+				"fzf " + fzfOption,
+			},
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+			wantFzfCommands: []string{
+				"fzf " + fzfOption,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := authorsCli{
+				revision:  "origin/master",
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "shortlog -sne origin/master":
+					return []byte(shortlog), nil
+				case "log --use-mailmap --format=%x00%aN <%aE>%x09%at --name-only origin/master":
+					return []byte(log), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			var gotFzfCommands []string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				gotFzfCommands = append(gotFzfCommands, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				if len(gotFzfCommands) == 1 {
+					list, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, "john@example.com\t    10\tJohn Doe <john@example.com>\t2020-01-02 - 2020-03-04\tmain.go (2), README.md (1)\njane@example.com\t     2\tJane Doe <jane@example.com>\n", string(list))
+				}
+				require.True(t, len(gotFzfCommands) <= len(tc.fzfOutputs))
+				return []byte(tc.fzfOutputs[len(gotFzfCommands)-1]), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantFzfCommands, gotFzfCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
+
+func TestAuthorStat_TopFiles(t *testing.T) {
+	stat := authorStat{
+		files: map[string]int{
+			"a.go": 1,
+			"b.go": 3,
+			"c.go": 1,
+			"d.go": 2,
+		},
+	}
+	assert.Equal(t, "b.go (3), d.go (2), a.go (1)", stat.topFiles(3))
+	assert.Equal(t, "b.go (3), d.go (2), a.go (1), c.go (1)", stat.topFiles(10))
+	assert.Equal(t, "", authorStat{}.topFiles(3))
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type bisectCli struct {
+	logOptions []string
+	logFile    string
+	fzfOption  string
+}
+
+const (
+	bisectKeyGood = "alt-g"
+	bisectKeyBad  = "alt-b"
+	bisectKeySkip = "alt-s"
+
+	bisectFirstBadCommitMessage = "is the first bad commit"
+	// git bisect exits with an error and lists candidates of the first bad commit after these messages
+	bisectOnlySkippedMessage = "There are only 'skip'ped commits left to test."
+	bisectCandidatesMessage  = "The first bad commit could be any of:"
+	bisectCannotMoreMessage  = "We cannot bisect more!"
+
+	bisectInProgressMessage = `git bisect is still in progress. Run "git bisect reset" to finish it`
+)
+
+func NewBisectSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "bisect [<git log options>]",
+		Short: "git bisect with fzf",
+		Args:  cobra.MaximumNArgs(100),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			logFile, err := flags.GetString("log-file")
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			if logFile == "" {
+				out, err := runGitCommandOutput(ctx, "rev-parse", "--git-path", "git-fzf-bisect.log")
+				if err != nil {
+					return fmt.Errorf("failed to get the path of the log file: %w", err)
+				}
+				logFile = strings.TrimSpace(string(out))
+			}
+
+			cli, err := newBisectCli(args, logFile, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(ctx, os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("log-file", "", "The file to save git bisect log. Default: git-fzf-bisect.log in the git directory")
+	return command
+}
+
+func newBisectCli(logOptions []string, logFile string, fzfQuery string) (*bisectCli, error) {
+	previewCommand, err := commandFromTemplate("preview", logFzfPreviewCommand, map[string]interface{}{
+		"path":        "{1}",
+		"objectRange": "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --no-multi --no-sort --expect %s,%s,%s", fzfOption, bisectKeyGood, bisectKeyBad, bisectKeySkip)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &bisectCli{
+		logOptions: logOptions,
+		logFile:    logFile,
+		fzfOption:  fzfOption,
+	}, nil
+}
+
+// Run starts bisect between bad and good commits picked by git fzf log,
+// and then a user marks the candidates until the first bad commit is found
+func (c bisectCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	bad, err := pickCommit(ctx, ioIn, ioErr, c.logOptions, "bad")
+	if err != nil || bad == "" {
+		return err
+	}
+	good, err := pickCommit(ctx, ioIn, ioErr, append([]string{bad + "^"}, c.logOptions...), "good")
+	if err != nil || good == "" {
+		return err
+	}
+
+	out, err := runGitCommandOutput(ctx, "bisect", "start", bad, good)
+	if err != nil {
+		return fmt.Errorf("failed to start bisect: %w", err)
+	}
+
+	for {
+		if culprit := bisectFirstBadCommit(out); culprit != "" {
+			return c.finish(ctx, []string{culprit}, out, ioOut, ioErr)
+		}
+
+		list, err := listBisectCandidates(ctx)
+		if err != nil {
+			return err
+		}
+		command := fmt.Sprintf("fzf %s --header %s", c.fzfOption, shellQuote(bisectStatus(out)))
+		fzfOut, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				_, err := fmt.Fprintln(ioErr, bisectInProgressMessage)
+				return err
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(fzfOut)
+		if len(lines) == 0 {
+			continue
+		}
+		commit := strings.Fields(lines[0])[0]
+
+		var term string
+		switch key {
+		case bisectKeyGood:
+			term = "good"
+		case bisectKeyBad:
+			term = "bad"
+		case bisectKeySkip:
+			term = "skip"
+		default:
+			_, err := fmt.Fprintln(ioErr, bisectInProgressMessage)
+			return err
+		}
+		out, err = runGitCommandOutput(ctx, "bisect", term, commit)
+		if err != nil {
+			if candidates := bisectSkippedCandidates(out); len(candidates) > 0 {
+				return c.finish(ctx, candidates, out, ioOut, ioErr)
+			}
+			if _, err := fmt.Fprintln(ioErr, bisectInProgressMessage); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return fmt.Errorf("failed to run git bisect %s %s: %w", term, commit, err)
+		}
+	}
+}
+
+// finish saves the log of bisect and resets it.
+// culprits are more than one if the first bad commit can't be found because of skipped commits.
+func (c bisectCli) finish(ctx context.Context, culprits []string, out []byte, ioOut io.Writer, ioErr io.Writer) error {
+	if _, err := ioErr.Write(out); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	log, err := runGitCommandOutput(ctx, "bisect", "log")
+	if err != nil {
+		return fmt.Errorf("failed to get the log of bisect: %w", err)
+	}
+	if err := ioutil.WriteFile(c.logFile, log, 0644); err != nil {
+		return fmt.Errorf("failed to save the log of bisect: %w", err)
+	}
+	if _, err := fmt.Fprintf(ioErr, "The log of bisect is saved in %s\n", c.logFile); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	if err := runGitCommand(ctx, nil, ioErr, ioErr, "bisect", "reset"); err != nil {
+		return fmt.Errorf("failed to reset bisect: %w", err)
+	}
+	if _, err := fmt.Fprintln(ioOut, strings.Join(culprits, "\n")); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// listBisectCandidates lists the current candidate first, and then the rest of the remaining commits
+func listBisectCandidates(ctx context.Context) ([]byte, error) {
+	current, err := runGitCommandOutput(ctx, "log", "-1", "--color", "--oneline", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current candidate: %w", err)
+	}
+	remaining, err := runGitCommandOutput(ctx, "bisect", "visualize", "--color", "--oneline")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the remaining commits: %w", err)
+	}
+	currentLine := string(current)
+	var list bytes.Buffer
+	list.WriteString(currentLine)
+	for _, line := range strings.SplitAfter(string(remaining), "\n") {
+		if line != "" && line != currentLine {
+			list.WriteString(line)
+		}
+	}
+	return list.Bytes(), nil
+}
+
+// bisectStatus returns the message about the remaining steps like
+// Bisecting: 3 revisions left to test after this (roughly 2 steps)
+func bisectStatus(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Bisecting:") {
+			return line
+		}
+	}
+	return "Bisecting"
+}
+
+func bisectFirstBadCommit(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasSuffix(line, bisectFirstBadCommitMessage) {
+			return strings.Fields(line)[0]
+		}
+	}
+	return ""
+}
+
+// bisectSkippedCandidates returns candidates of the first bad commit if only skipped commits are left
+func bisectSkippedCandidates(out []byte) []string {
+	if !strings.Contains(string(out), bisectOnlySkippedMessage) {
+		return nil
+	}
+	var candidates []string
+	isCandidate := false
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case line == bisectCandidatesMessage:
+			isCandidate = true
+		case line == bisectCannotMoreMessage:
+			isCandidate = false
+		case isCandidate && line != "":
+			candidates = append(candidates, strings.Fields(line)[0])
+		}
+	}
+	return candidates
+}
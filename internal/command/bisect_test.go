@@ -0,0 +1,290 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBisectSubcommand(t *testing.T) {
+	assert.NotNil(t, NewBisectSubcommand())
+}
+
+func TestNewBisectCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		logOptions []string
+		logFile    string
+		fzfQuery   string
+		envVars    map[string]string
+		want       *bisectCli
+		wantErr    error
+	}{
+		{
+			name:       "no options",
+			logOptions: []string{},
+			logFile:    ".git/git-fzf-bisect.log",
+			fzfQuery:   "",
+			want: &bisectCli{
+				logOptions: []string{},
+				logFile:    ".git/git-fzf-bisect.log",
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --no-sort --expect alt-g,alt-b,alt-s", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "all options",
+			logOptions: []string{"--", "README.md"},
+			logFile:    "bisect.log",
+			fzfQuery:   "fix",
+			want: &bisectCli{
+				logOptions: []string{"--", "README.md"},
+				logFile:    "bisect.log",
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --no-sort --expect alt-g,alt-b,alt-s --query fix", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "GIT_FZF_FZF_OPTION includes invalid env",
+			logOptions: []string{},
+			logFile:    "bisect.log",
+			fzfQuery:   "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newBisectCli(tc.logOptions, tc.logFile, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestBisectCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	startOutput := "Bisecting: 3 revisions left to test after this (roughly 2 steps)\n[ccc] c3\n"
+	gitOutputs := map[string]string{
+		"bisect start eee aaa":                 startOutput,
+		"log -1 --color --oneline HEAD":        "ccc c3\n",
+		"bisect visualize --color --oneline":   "eee c5\nddd c4\nccc c3\nbbb c2\n",
+		"bisect good ccc":                      "Bisecting: 0 revisions left to test after this (roughly 0 steps)\n[ddd] c4\n",
+		"bisect bad ccc":                       "ccc is the first bad commit\ncommit ccc\n",
+		"bisect log":                           "git bisect start 'eee' 'aaa'\n",
+		"log -1 --color --oneline HEAD (good)": "ddd c4\n",
+	}
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		wantErr         error
+		wantGitCommands []string
+		wantLog         string
+		wantIO          string
+		wantIOErr       string
+		// skipOutput is the output of git bisect skip, which exits with an error
+		skipOutput string
+	}{
+		{
+			name: "first bad commit is found",
+			fzfOutputs: []string{
+				"eee c5\n",
+				"aaa c1\n",
+				"alt-b\nccc c3\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"bisect reset",
+			},
+			wantLog:   "git bisect start 'eee' 'aaa'\n",
+			wantIO:    "ccc\n",
+			wantIOErr: "ccc is the first bad commit\ncommit ccc\nThe log of bisect is saved in ",
+		},
+		{
+			name: "stopped in progress",
+			fzfOutputs: []string{
+				"eee c5\n",
+				"aaa c1\n",
+				"alt-g\nccc c3\n",
+				"\nddd c4\n",
+			},
+			wantErr:   nil,
+			wantIO:    "",
+			wantIOErr: "git bisect is still in progress. Run \"git bisect reset\" to finish it\n",
+		},
+		{
+			name: "only skipped commits are left",
+			fzfOutputs: []string{
+				"eee c5\n",
+				"aaa c1\n",
+				"alt-s\nccc c3\n",
+			},
+			skipOutput: "There are only 'skip'ped commits left to test.\nThe first bad commit could be any of:\nccc\nddd\nWe cannot bisect more!\n",
+			wantErr:    nil,
+			wantGitCommands: []string{
+				"bisect reset",
+			},
+			wantLog:   "git bisect start 'eee' 'aaa'\n",
+			wantIO:    "ccc\nddd\n",
+			wantIOErr: "There are only 'skip'ped commits left to test.\nThe first bad commit could be any of:\nccc\nddd\nWe cannot bisect more!\nThe log of bisect is saved in ",
+		},
+		{
+			name: "git bisect error",
+			fzfOutputs: []string{
+				"eee c5\n",
+				"aaa c1\n",
+				"alt-s\nccc c3\n",
+			},
+			skipOutput: "",
+			wantErr:    defaultWantErr,
+			wantIO:     "",
+			wantIOErr:  "git bisect is still in progress. Run \"git bisect reset\" to finish it\n",
+		},
+		{
+			name:      "command with fzf error",
+			fzfErr:    defaultWantErr,
+			wantErr:   defaultWantErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+		{
+			name:      "command with fzf exit error (not 130)",
+			fzfErr:    &exitErr,
+			wantErr:   &exitErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "git-fzf")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			sut := bisectCli{
+				logOptions: []string{},
+				logFile:    filepath.Join(dir, "bisect.log"),
+				fzfOption:  fzfOption,
+			}
+
+			fzfCount := 0
+			var gotFzfCommands []string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				gotFzfCommands = append(gotFzfCommands, commandLine)
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			marked := ""
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				command := strings.Join(args, " ")
+				if command == "bisect good ccc" {
+					marked = " (good)"
+				}
+				if command == "bisect skip ccc" {
+					return []byte(tc.skipOutput), defaultWantErr
+				}
+				if out, ok := gitOutputs[command+marked]; ok {
+					return []byte(out), nil
+				}
+				out, ok := gitOutputs[command]
+				require.True(t, ok, command)
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.True(t, strings.HasPrefix(gotIOErr.String(), tc.wantIOErr), gotIOErr.String())
+			if tc.wantLog != "" {
+				gotLog, err := ioutil.ReadFile(sut.logFile)
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantLog, string(gotLog))
+			}
+			if len(tc.fzfOutputs) > 2 {
+				assert.Equal(t, "fzf --inline-info --header 'Bisecting: 3 revisions left to test after this (roughly 2 steps)'", gotFzfCommands[2])
+			}
+		})
+	}
+}
+
+func TestListBisectCandidates(t *testing.T) {
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "log -1 --color --oneline HEAD":
+			return []byte("ccc c3\n"), nil
+		case "bisect visualize --color --oneline":
+			return []byte("eee c5\nddd c4\nccc c3\nbbb c2\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	}
+	got, gotErr := listBisectCandidates(context.Background())
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "ccc c3\neee c5\nddd c4\nbbb c2\n", string(got))
+}
+
+func TestBisectSkippedCandidates(t *testing.T) {
+	testCases := []struct {
+		name string
+		out  string
+		want []string
+	}{
+		{
+			name: "only skipped commits are left",
+			out:  "There are only 'skip'ped commits left to test.\nThe first bad commit could be any of:\nccc\nddd\nWe cannot bisect more!\n",
+			want: []string{"ccc", "ddd"},
+		},
+		{
+			name: "bisecting",
+			out:  "Bisecting: 0 revisions left to test after this (roughly 0 steps)\n[ddd] c4\n",
+			want: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, bisectSkippedCandidates([]byte(tc.out)))
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type blameCli struct {
+	path        string
+	revision    string
+	listCommand string
+	fzfOption   string
+}
+
+type blameLine struct {
+	commit     string
+	path       string
+	author     string
+	authorTime time.Time
+	lineNumber int
+	content    string
+}
+
+const (
+	// Lines not committed yet have the hash of zeros
+	blameUncommittedCommit = "0000000"
+)
+
+func NewBlameSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "blame [<rev>] <file>",
+		Short: "git blame with fzf",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			isList, err := flags.GetBool("list")
+			if err != nil {
+				return err
+			}
+			revision := ""
+			path := args[len(args)-1]
+			if len(args) == 2 {
+				revision = args[0]
+			}
+			previewCommit, err := flags.GetString("preview")
+			if err != nil {
+				return err
+			}
+			if previewCommit != "" {
+				return previewBlameCommit(context.Background(), os.Stdout, os.Stderr, previewCommit, path)
+			}
+			if isList {
+				parentOf, err := flags.GetString("parent-of")
+				if err != nil {
+					return err
+				}
+				if parentOf == "" {
+					return listBlameLines(context.Background(), os.Stdout, "", revision, path)
+				}
+				return listBlameParentLines(context.Background(), os.Stdout, parentOf, path)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newBlameCli(revision, path, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.Bool("list", false, "Only print the lines of git blame for fzf")
+	flags.String("parent-of", "", "Print the lines of the parent of this commit by --list, for the path relative to the top directory")
+	flags.String("preview", "", "Only print this commit, or the diff of the path relative to the top directory for uncommitted lines")
+	_ = flags.MarkHidden("list")
+	_ = flags.MarkHidden("parent-of")
+	_ = flags.MarkHidden("preview")
+	return command
+}
+
+func newBlameCli(revision string, path string, fzfQuery string) (*blameCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	listCommand := shellQuote(self) + " blame --list"
+
+	// Paths in the list are relative to the top directory.
+	// The parent is blamed with the path of the focused line, which is the path at that commit.
+	// These aren't templates because quotes are escaped by html/template.
+	previewCommand := fmt.Sprintf("%s blame --preview {1} {2}", shellQuoteInFzfOption(self))
+	bindOption := fmt.Sprintf("alt-b:reload(%s blame --list --parent-of {1} {2})", shellQuoteInFzfOption(self))
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --delimiter '\\t' --with-nth 1,3.. --bind '" + bindOption + "'"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &blameCli{
+		path:        path,
+		revision:    revision,
+		listCommand: listCommand,
+		fzfOption:   fzfOption,
+	}, nil
+}
+
+func (c blameCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	command := c.listCommand
+	if c.revision != "" {
+		command = command + " " + shellQuote(c.revision)
+	}
+	command = fmt.Sprintf("%s %s | fzf %s", command, shellQuote(c.path), c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	if err := writeFzfResult(ioOut, out, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listBlameLines prints lines of git blame run in the directory, or the current directory if it's empty
+func listBlameLines(ctx context.Context, ioOut io.Writer, dir string, revision string, path string) error {
+	args := []string{"blame", "--porcelain"}
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	if revision != "" {
+		args = append(args, revision)
+	}
+	args = append(args, "--", path)
+	out, err := runGitCommandOutput(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("failed to run git blame: %w", err)
+	}
+	lines, err := parseBlamePorcelain(out)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		commit := line.commit
+		if len(commit) > shortHashLength {
+			commit = commit[:shortHashLength]
+		}
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			commit,
+			line.path,
+			line.author,
+			line.authorTime.Format("2006-01-02"),
+			line.lineNumber,
+			line.content,
+		)
+	}
+	if _, err := ioOut.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// listBlameParentLines prints lines of git blame for the parent of a commit.
+// Uncommitted lines are blamed at HEAD, and a root commit, which has no parent, is blamed at itself.
+func listBlameParentLines(ctx context.Context, ioOut io.Writer, commit string, path string) error {
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+	revision := commit + "^"
+	if commit == blameUncommittedCommit {
+		revision = "HEAD"
+	} else if _, err := runGitCommandOutput(ctx, "rev-parse", "--verify", "--quiet", revision); err != nil {
+		revision = commit
+	}
+	return listBlameLines(ctx, ioOut, top, revision, path)
+}
+
+// previewBlameCommit prints a commit, or the diff of a file from HEAD for uncommitted lines
+func previewBlameCommit(ctx context.Context, ioOut io.Writer, ioErr io.Writer, commit string, path string) error {
+	args := []string{"show", "--color", commit}
+	if commit == blameUncommittedCommit {
+		top, err := gitTopLevel(ctx)
+		if err != nil {
+			return err
+		}
+		args = []string{"-C", top, "diff", "--color", "HEAD", "--", path}
+	}
+	if err := runGitCommand(ctx, nil, ioOut, ioErr, args...); err != nil {
+		return fmt.Errorf("failed to preview %s: %w", commit, err)
+	}
+	return nil
+}
+
+// parseBlamePorcelain parses the output of git blame --porcelain.
+// The information of a commit is only written at the first line of the commit.
+func parseBlamePorcelain(out []byte) ([]blameLine, error) {
+	commits := map[string]*blameLine{}
+	var lines []blameLine
+	var current *blameLine
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			if current == nil {
+				return nil, fmt.Errorf("no header for the line: %s", text)
+			}
+			line := *current
+			line.lineNumber = lineNumber
+			line.content = text[1:]
+			lines = append(lines, line)
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid header of git blame: %s", text)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid line number of git blame: %s: %w", text, err)
+			}
+			lineNumber = n
+			commit, ok := commits[fields[0]]
+			if !ok {
+				commit = &blameLine{commit: fields[0]}
+				commits[fields[0]] = commit
+			}
+			current = commit
+			continue
+		}
+
+		keyValue := strings.SplitN(text, " ", 2)
+		value := ""
+		if len(keyValue) == 2 {
+			value = keyValue[1]
+		}
+		switch keyValue[0] {
+		case "author":
+			current.author = value
+		case "author-time":
+			unixTime, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid author-time of git blame: %s: %w", value, err)
+			}
+			current.authorTime = time.Unix(unixTime, 0).In(current.authorTime.Location())
+		case "author-tz":
+			current.authorTime = current.authorTime.In(parseTimezone(value))
+		case "filename":
+			current.path = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the output of git blame: %w", err)
+	}
+	return lines, nil
+}
+
+// parseTimezone parses the timezone like +0900 of git
+func parseTimezone(tz string) *time.Location {
+	if len(tz) != 5 {
+		return time.UTC
+	}
+	hours, err := strconv.Atoi(tz[1:3])
+	if err != nil {
+		return time.UTC
+	}
+	minutes, err := strconv.Atoi(tz[3:5])
+	if err != nil {
+		return time.UTC
+	}
+	offset := hours*60*60 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(tz, offset)
+}
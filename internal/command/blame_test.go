@@ -0,0 +1,357 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBlameSubcommand(t *testing.T) {
+	assert.NotNil(t, NewBlameSubcommand())
+}
+
+func TestNewBlameCommand(t *testing.T) {
+	previewCommand := `'\''git-fzf'\'' blame --preview {1} {2}`
+	bindOption := `alt-b:reload('\''git-fzf'\'' blame --list --parent-of {1} {2})`
+
+	testCases := []struct {
+		name     string
+		revision string
+		path     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *blameCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			revision: "",
+			path:     "README.md",
+			fzfQuery: "",
+			want: &blameCli{
+				path:        "README.md",
+				revision:    "",
+				listCommand: "'git-fzf' blame --list",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 1,3.. --bind '%s'", previewCommand, defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			revision: "origin/master",
+			path:     "README.md",
+			fzfQuery: "config",
+			want: &blameCli{
+				path:        "README.md",
+				revision:    "origin/master",
+				listCommand: "'git-fzf' blame --list",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 1,3.. --bind '%s' --query config", previewCommand, defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			revision: "",
+			path:     "README.md",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newBlameCli(tc.revision, tc.path, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestBlameCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultRunCommand := func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+		assert.Equal(t, fmt.Sprintf("%s | fzf %s",
+			"'git-fzf' blame --list 'origin/master' 'README.md'",
+			fzfOption,
+		), commandLine)
+		return bytes.NewBufferString("abc1234\tREADME.md\tauthor\t2020-01-01\t1\t# git fzf\nxyz5678\tREADME.md\tauthor\t2020-01-02\t2\tplugin\n").Bytes(), nil
+	}
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		sut               blameCli
+		wantErr           error
+		wantIO            string
+		wantIOErr         string
+	}{
+		{
+			name: "commit output",
+			sut: blameCli{
+				path:        "README.md",
+				revision:    "origin/master",
+				listCommand: "'git-fzf' blame --list",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: defaultRunCommand,
+			wantErr:           nil,
+			wantIO:            "abc1234\nxyz5678\n",
+			wantIOErr:         "",
+		},
+		{
+			name: "command with fzf error",
+			sut: blameCli{
+				path:        "README.md",
+				listCommand: "'git-fzf' blame --list",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, defaultWantErr
+			},
+			wantErr:   defaultWantErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: blameCli{
+				path:        "README.md",
+				listCommand: "'git-fzf' blame --list",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, &exitErr
+			},
+			wantErr:   &exitErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestListBlameLines(t *testing.T) {
+	porcelain := `4c2a3e1d0f9b8a7c6d5e4f3a2b1c0d9e8f7a6b5c 1 1 2
+author Alice
+author-mail <alice@example.com>
+author-time 1577836800
+author-tz +0900
+summary Initial commit
+boundary
+filename README.md
+	# git fzf
+4c2a3e1d0f9b8a7c6d5e4f3a2b1c0d9e8f7a6b5c 2 2
+	plugin
+9f8e7d6c5b4a39281706f5e4d3c2b1a098765432 1 3 1
+author Bob
+author-mail <bob@example.com>
+author-time 1577923200
+author-tz -0500
+summary Rename
+filename doc/README.md
+	with fzf
+`
+
+	testCases := []struct {
+		name                string
+		dir                 string
+		revision            string
+		runGitCommandOutput func(ctx context.Context, args ...string) ([]byte, error)
+		wantIO              string
+		wantIsErr           bool
+	}{
+		{
+			name:     "lines",
+			revision: "origin/master",
+			runGitCommandOutput: func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, []string{"blame", "--porcelain", "origin/master", "--", "README.md"}, args)
+				return []byte(porcelain), nil
+			},
+			wantIO:    "4c2a3e1\tREADME.md\tAlice\t2020-01-01\t1\t# git fzf\n4c2a3e1\tREADME.md\tAlice\t2020-01-01\t2\tplugin\n9f8e7d6\tdoc/README.md\tBob\t2020-01-01\t3\twith fzf\n",
+			wantIsErr: false,
+		},
+		{
+			name:     "in the top directory",
+			dir:      "/repo",
+			revision: "abc^",
+			runGitCommandOutput: func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, []string{"-C", "/repo", "blame", "--porcelain", "abc^", "--", "README.md"}, args)
+				return []byte(porcelain), nil
+			},
+			wantIO:    "4c2a3e1\tREADME.md\tAlice\t2020-01-01\t1\t# git fzf\n4c2a3e1\tREADME.md\tAlice\t2020-01-01\t2\tplugin\n9f8e7d6\tdoc/README.md\tBob\t2020-01-01\t3\twith fzf\n",
+			wantIsErr: false,
+		},
+		{
+			name:     "git blame error",
+			revision: "",
+			runGitCommandOutput: func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, []string{"blame", "--porcelain", "--", "README.md"}, args)
+				return nil, errors.New("error")
+			},
+			wantIO:    "",
+			wantIsErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = tc.runGitCommandOutput
+
+			var gotIOOut bytes.Buffer
+			gotErr := listBlameLines(context.Background(), &gotIOOut, tc.dir, tc.revision, "README.md")
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+		})
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tz         string
+		wantOffset int
+	}{
+		{
+			name:       "positive",
+			tz:         "+0930",
+			wantOffset: 9*60*60 + 30*60,
+		},
+		{
+			name:       "negative",
+			tz:         "-0500",
+			wantOffset: -5 * 60 * 60,
+		},
+		{
+			name:       "invalid",
+			tz:         "JST",
+			wantOffset: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, gotOffset := time.Unix(0, 0).In(parseTimezone(tc.tz)).Zone()
+			assert.Equal(t, tc.wantOffset, gotOffset)
+		})
+	}
+}
+
+func TestListBlameParentLines(t *testing.T) {
+	testCases := []struct {
+		name         string
+		commit       string
+		hasParent    bool
+		wantRevision string
+	}{
+		{
+			name:         "commit",
+			commit:       "abc1234",
+			hasParent:    true,
+			wantRevision: "abc1234^",
+		},
+		{
+			name:         "root commit",
+			commit:       "abc1234",
+			hasParent:    false,
+			wantRevision: "abc1234",
+		},
+		{
+			name:         "uncommitted lines",
+			commit:       "0000000",
+			wantRevision: "HEAD",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				case "rev-parse --verify --quiet abc1234^":
+					if tc.hasParent {
+						return []byte("def\n"), nil
+					}
+					return nil, errors.New("exit status 1")
+				}
+				assert.Equal(t, []string{"-C", "/repo", "blame", "--porcelain", tc.wantRevision, "--", "dir/main.go"}, args)
+				return []byte(""), nil
+			}
+			assert.NoError(t, listBlameParentLines(context.Background(), ioutil.Discard, tc.commit, "dir/main.go"))
+		})
+	}
+}
+
+func TestPreviewBlameCommit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		commit   string
+		wantArgs []string
+	}{
+		{
+			name:     "commit",
+			commit:   "abc1234",
+			wantArgs: []string{"show", "--color", "abc1234"},
+		},
+		{
+			name:     "uncommitted lines",
+			commit:   "0000000",
+			wantArgs: []string{"-C", "/repo", "diff", "--color", "HEAD", "--", "dir/main.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, "rev-parse --show-toplevel", strings.Join(args, " "))
+				return []byte("/repo\n"), nil
+			}
+			var gotArgs []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotArgs = args
+				return nil
+			}
+			assert.NoError(t, previewBlameCommit(context.Background(), ioutil.Discard, ioutil.Discard, tc.commit, "dir/main.go"))
+			assert.Equal(t, tc.wantArgs, gotArgs)
+		})
+	}
+}
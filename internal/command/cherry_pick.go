@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type cherryPickCli struct {
+	ref       string
+	fzfOption string
+}
+
+type cherryPickCommit struct {
+	hash      string
+	shortHash string
+	subject   string
+	applied   bool
+}
+
+const (
+	cherryPickAppliedMark = "applied"
+)
+
+func NewCherryPickSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cherry-pick <ref>",
+		Short: "git cherry-pick commits of another ref with fzf",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newCherryPickCli(args[0], fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newCherryPickCli(ref string, fzfQuery string) (*cherryPickCli, error) {
+	previewCommand, err := commandFromTemplate("preview", logFzfPreviewCommand, map[string]interface{}{
+		"path":        "{1}",
+		"objectRange": "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --delimiter '\\t'"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &cherryPickCli{
+		ref:       ref,
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c cherryPickCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	commits, err := listCherryPickCommits(ctx, c.ref)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		_, err := fmt.Fprintf(ioErr, "No commit to cherry-pick from %s\n", c.ref)
+		return err
+	}
+	var list bytes.Buffer
+	for _, commit := range commits {
+		mark := ""
+		if commit.applied {
+			mark = cherryPickAppliedMark
+		}
+		fmt.Fprintf(&list, "%s\t%s\t%s\n", commit.shortHash, mark, commit.subject)
+	}
+
+	command := fmt.Sprintf("fzf %s", c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	selected := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		selected[strings.SplitN(line, "\t", 2)[0]] = true
+	}
+
+	// fzf returns commits in the order of selection, so they're sorted by the topological order of the list
+	var hashes []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		if selected[commits[i].shortHash] {
+			hashes = append(hashes, commits[i].hash)
+		}
+	}
+	args := append([]string{"cherry-pick"}, hashes...)
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+		_, _ = fmt.Fprintln(ioErr, "git cherry-pick stopped. Resolve the conflicts and run \"git cherry-pick --continue\", or run \"git cherry-pick --abort\"")
+		return fmt.Errorf("failed to cherry-pick %s: %w", strings.Join(hashes, " "), err)
+	}
+	if _, err := io.WriteString(ioOut, strings.Join(hashes, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// listCherryPickCommits lists commits in HEAD..<ref> from the newest one in the topological order.
+// Merge commits aren't listed because they can't be cherry-picked without a mainline.
+// A commit is marked as applied if the same patch is already in HEAD.
+func listCherryPickCommits(ctx context.Context, ref string) ([]cherryPickCommit, error) {
+	out, err := runGitCommandOutput(ctx, "log", "--topo-order", "--no-merges", "--format=%H%x09%h%x09%s", "HEAD.."+ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits of %s: %w", ref, err)
+	}
+	cherry, err := runGitCommandOutput(ctx, "cherry", "HEAD", ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git cherry for %s: %w", ref, err)
+	}
+	applied := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(cherry))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "-" {
+			applied[fields[1]] = true
+		}
+	}
+
+	var commits []cherryPickCommit
+	scanner = bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, cherryPickCommit{
+			hash:      fields[0],
+			shortHash: fields[1],
+			subject:   fields[2],
+			applied:   applied[fields[0]],
+		})
+	}
+	return commits, nil
+}
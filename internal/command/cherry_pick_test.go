@@ -0,0 +1,192 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCherryPickSubcommand(t *testing.T) {
+	assert.NotNil(t, NewCherryPickSubcommand())
+}
+
+func TestNewCherryPickCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ref      string
+		fzfQuery string
+		envVars  map[string]string
+		want     *cherryPickCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			ref:      "feature",
+			fzfQuery: "",
+			want: &cherryPickCli{
+				ref:       "feature",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t'", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			ref:      "feature",
+			fzfQuery: "fix",
+			want: &cherryPickCli{
+				ref:       "feature",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --query fix", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			ref:      "feature",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newCherryPickCli(tc.ref, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestCherryPickCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	gitLog := "cccccccccc\tccccccc\tThird\nbbbbbbbbbb\tbbbbbbb\tSecond\naaaaaaaaaa\taaaaaaa\tFirst\n"
+	gitCherry := "+ aaaaaaaaaa\n- bbbbbbbbbb\n+ cccccccccc\n"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		gitErr            error
+		sut               cherryPickCli
+		wantErr           error
+		wantGitCommands   []string
+		wantIO            string
+		wantIOErr         string
+	}{
+		{
+			name: "cherry-pick from the oldest commit",
+			sut: cherryPickCli{
+				ref:       "feature",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				assert.Equal(t, "fzf --inline-info", commandLine)
+				in, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, "ccccccc\t\tThird\nbbbbbbb\tapplied\tSecond\naaaaaaa\t\tFirst\n", string(in))
+				return bytes.NewBufferString("ccccccc\t\tThird\naaaaaaa\t\tFirst\n").Bytes(), nil
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"cherry-pick aaaaaaaaaa cccccccccc",
+			},
+			wantIO:    "aaaaaaaaaa\ncccccccccc\n",
+			wantIOErr: "",
+		},
+		{
+			name: "conflict",
+			sut: cherryPickCli{
+				ref:       "feature",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return bytes.NewBufferString("bbbbbbb\tapplied\tSecond\n").Bytes(), nil
+			},
+			gitErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+			wantGitCommands: []string{
+				"cherry-pick bbbbbbbbbb",
+			},
+			wantIO:    "",
+			wantIOErr: "git cherry-pick stopped. Resolve the conflicts and run \"git cherry-pick --continue\", or run \"git cherry-pick --abort\"\n",
+		},
+		{
+			name: "command with fzf error",
+			sut: cherryPickCli{
+				ref:       "feature",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, defaultWantErr
+			},
+			wantErr:   defaultWantErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: cherryPickCli{
+				ref:       "feature",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, &exitErr
+			},
+			wantErr:   &exitErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "log --topo-order --no-merges --format=%H%x09%h%x09%s HEAD..feature":
+					return []byte(gitLog), nil
+				case "cherry HEAD feature":
+					return []byte(gitCherry), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return tc.gitErr
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type cleanCli struct {
+	listCommand string
+	fzfOption   string
+}
+
+const (
+	cleanFzfPreviewCommand = "if [ -d {{.path}} ]; then ls -la {{.path}}; else bat --color=always --style=numbers {{.path}} 2>/dev/null || cat {{.path}}; fi"
+
+	cleanTimeFormat = "2006-01-02 15:04"
+)
+
+func NewCleanSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "clean",
+		Short: "git clean for untracked files selected with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			ignored, err := flags.GetBool("ignored")
+			if err != nil {
+				return err
+			}
+			isList, err := flags.GetBool("list")
+			if err != nil {
+				return err
+			}
+			if isList {
+				return listCleanEntries(context.Background(), os.Stdout, ignored)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newCleanCli(ignored, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.BoolP("ignored", "i", false, "Show ignored files as well")
+	flags.Bool("list", false, "Only print untracked files with their size and modified time for fzf")
+	_ = flags.MarkHidden("list")
+	return command
+}
+
+func newCleanCli(ignored bool, fzfQuery string) (*cleanCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	// The command is quoted again in the bind option of fzf
+	listCommand := func(ignored bool, quote func(string) string) string {
+		if ignored {
+			return quote(self) + " clean --list --ignored"
+		}
+		return quote(self) + " clean --list"
+	}
+
+	previewCommand, err := commandFromTemplate("preview", cleanFzfPreviewCommand, map[string]interface{}{
+		"path": "{3}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	// Ignored files can be shown or hidden while fzf is running
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --nth 3 --bind 'alt-i:reload(%s),alt-u:reload(%s)'", fzfOption, listCommand(true, shellQuoteInFzfOption), listCommand(false, shellQuoteInFzfOption))
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &cleanCli{
+		listCommand: listCommand(ignored, shellQuote),
+		fzfOption:   fzfOption,
+	}, nil
+}
+
+func (c cleanCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	command := fmt.Sprintf("%s | fzf %s", c.listCommand, c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+
+	var summary strings.Builder
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		paths = append(paths, fields[2])
+		fmt.Fprintf(&summary, "  %s (%s)\n", fields[2], strings.TrimSpace(fields[0]))
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(ioErr, "The following entries will be removed (%d):\n%s", len(paths), summary.String()); err != nil {
+		return fmt.Errorf("failed to output the summary: %w", err)
+	}
+	ok, err := confirm(ioIn, ioErr, "Remove them?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	// -x is only for ignored entries, so that ignored files in a selected untracked directory aren't removed
+	ignored, err := checkIgnoredPaths(ctx, paths)
+	if err != nil {
+		return err
+	}
+	var untrackedPaths []string
+	var ignoredPaths []string
+	for _, path := range paths {
+		if ignored[path] {
+			ignoredPaths = append(ignoredPaths, path)
+		} else {
+			untrackedPaths = append(untrackedPaths, path)
+		}
+	}
+	for _, clean := range []struct {
+		options []string
+		paths   []string
+	}{
+		{options: []string{"-f", "-d"}, paths: untrackedPaths},
+		{options: []string{"-f", "-d", "-x"}, paths: ignoredPaths},
+	} {
+		if len(clean.paths) == 0 {
+			continue
+		}
+		// Paths are treated literally, so that a path like * doesn't remove other files
+		args := append([]string{"--literal-pathspecs", "clean"}, clean.options...)
+		args = append(append(args, "--"), clean.paths...)
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+			return fmt.Errorf("failed to remove files: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkIgnoredPaths returns paths which are ignored by git check-ignore
+func checkIgnoredPaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	err := runGitCommand(ctx, in, &out, ioutil.Discard, "check-ignore", "--stdin", "-z")
+	// git check-ignore exits with 1 if no path is ignored
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, fmt.Errorf("failed to check ignored files: %w", err)
+	}
+	ignored := map[string]bool{}
+	for _, path := range strings.Split(out.String(), "\x00") {
+		if path != "" {
+			ignored[path] = true
+		}
+	}
+	return ignored, nil
+}
+
+// listCleanEntries prints untracked files and directories like <size><tab><modified time><tab><path>.
+// A directory which has only untracked files is printed as one entry.
+func listCleanEntries(ctx context.Context, ioOut io.Writer, ignored bool) error {
+	commands := [][]string{
+		{"ls-files", "-z", "--others", "--exclude-standard", "--directory"},
+	}
+	if ignored {
+		commands = append(commands, []string{"ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory"})
+	}
+
+	var buf bytes.Buffer
+	// An entry may be listed by both commands
+	listed := map[string]bool{}
+	for _, args := range commands {
+		out, err := runGitCommandOutput(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list untracked files: %w", err)
+		}
+		for _, path := range strings.Split(string(out), "\x00") {
+			if path == "" || listed[path] {
+				continue
+			}
+			listed[path] = true
+			info, err := os.Lstat(path)
+			if err != nil {
+				return fmt.Errorf("failed to get the information of %s: %w", path, err)
+			}
+			size := info.Size()
+			if info.IsDir() {
+				size = directorySize(path)
+			}
+			fmt.Fprintf(&buf, "%6s\t%s\t%s\n", formatFileSize(size), info.ModTime().Format(cleanTimeFormat), path)
+		}
+	}
+	if _, err := ioOut.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// directorySize returns the total size of files under a directory, ignoring files which cannot be read
+func directorySize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// formatFileSize formats a size in bytes like ls -h
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	suffixes := []string{"K", "M", "G", "T"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f%s", value, suffixes[i])
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCleanSubcommand(t *testing.T) {
+	assert.NotNil(t, NewCleanSubcommand())
+}
+
+func TestNewCleanCommand(t *testing.T) {
+	previewCommand := "if [ -d {3} ]; then ls -la {3}; else bat --color=always --style=numbers {3} 2>/dev/null || cat {3}; fi"
+	bindOption := `alt-i:reload('\''git-fzf'\'' clean --list --ignored),alt-u:reload('\''git-fzf'\'' clean --list)`
+
+	testCases := []struct {
+		name     string
+		ignored  bool
+		fzfQuery string
+		envVars  map[string]string
+		want     *cleanCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			ignored:  false,
+			fzfQuery: "",
+			want: &cleanCli{
+				listCommand: "'git-fzf' clean --list",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --nth 3 --bind '%s'", previewCommand, defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			ignored:  true,
+			fzfQuery: "build",
+			want: &cleanCli{
+				listCommand: "'git-fzf' clean --list --ignored",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --nth 3 --bind '%s' --query build", previewCommand, defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newCleanCli(tc.ignored, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestCleanCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	listCommand := "git-fzf clean --list"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name            string
+		fzfOutput       string
+		fzfErr          error
+		ioIn            string
+		wantErr         error
+		wantGitCommands []string
+		wantIOErr       string
+	}{
+		{
+			name:      "remove selected entries",
+			fzfOutput: "  2.9K\t2020-01-02 03:04\tbuild/\n    3B\t2020-01-02 03:04\tmy file.txt\n",
+			ioIn:      "y\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"check-ignore --stdin -z",
+				"--literal-pathspecs clean -f -d -- my file.txt",
+				"--literal-pathspecs clean -f -d -x -- build/",
+			},
+			wantIOErr: "The following entries will be removed (2):\n  build/ (2.9K)\n  my file.txt (3B)\nRemove them? (y/N): ",
+		},
+		{
+			name:      "remove only untracked entries",
+			fzfOutput: "    3B\t2020-01-02 03:04\tmy file.txt\n",
+			ioIn:      "y\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"check-ignore --stdin -z",
+				"--literal-pathspecs clean -f -d -- my file.txt",
+			},
+			wantIOErr: "The following entries will be removed (1):\n  my file.txt (3B)\nRemove them? (y/N): ",
+		},
+		{
+			name:      "not confirmed",
+			fzfOutput: "    3B\t2020-01-02 03:04\tdebug.log\n",
+			ioIn:      "\n",
+			wantErr:   nil,
+			wantIOErr: "The following entries will be removed (1):\n  debug.log (3B)\nRemove them? (y/N): ",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := cleanCli{
+				listCommand: listCommand,
+				fzfOption:   fzfOption,
+			}
+
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, fmt.Sprintf("%s | fzf %s", listCommand, fzfOption), commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				return []byte(tc.fzfOutput), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				if args[0] != "check-ignore" {
+					return nil
+				}
+				in, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				if !strings.Contains(string(in), "build/\x00") {
+					// No path is ignored
+					return exec.Command("sh", "-c", "exit 1").Run()
+				}
+				_, err = io.WriteString(ioOut, "build/\x00")
+				return err
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, "", gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestListCleanEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "build", "sub"), 0755))
+	for path, size := range map[string]int{
+		filepath.Join("build", "a.bin"):        1000,
+		filepath.Join("build", "sub", "b.bin"): 2000,
+		"debug.log":                            10,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), make([]byte, size), 0644))
+	}
+	for _, path := range []string{"build", "debug.log"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, path), modTime, modTime))
+	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	require.NoError(t, os.Chdir(dir))
+
+	testCases := []struct {
+		name    string
+		ignored bool
+		want    string
+	}{
+		{
+			name:    "untracked files",
+			ignored: false,
+			want:    "  2.9K\t2020-01-02 03:04\tbuild/\n",
+		},
+		{
+			name:    "untracked and ignored files",
+			ignored: true,
+			want:    "  2.9K\t2020-01-02 03:04\tbuild/\n   10B\t2020-01-02 03:04\tdebug.log\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "ls-files -z --others --exclude-standard --directory":
+					return []byte("build/\x00"), nil
+				case "ls-files -z --others --ignored --exclude-standard --directory":
+					return []byte("build/\x00debug.log\x00"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+
+			var gotIOOut bytes.Buffer
+			require.NoError(t, listCleanEntries(context.Background(), &gotIOOut, tc.ignored))
+			assert.Equal(t, tc.want, gotIOOut.String())
+		})
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	assert.Equal(t, "0B", formatFileSize(0))
+	assert.Equal(t, "1023B", formatFileSize(1023))
+	assert.Equal(t, "1.0K", formatFileSize(1024))
+	assert.Equal(t, "1.5M", formatFileSize(1024*1024*3/2))
+	assert.Equal(t, "2.0G", formatFileSize(2*1024*1024*1024))
+}
@@ -0,0 +1,122 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type compareCli struct {
+	base     string
+	head     string
+	fzfQuery string
+}
+
+const (
+	compareKeySwap = "alt-s"
+)
+
+func NewCompareSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "compare [<base> [<head>]]",
+		Short: "git diff between two refs selected with fzf",
+		Args:  cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			cli := newCompareCli(args, fzfQuery)
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newCompareCli(refs []string, fzfQuery string) *compareCli {
+	cli := &compareCli{
+		fzfQuery: fzfQuery,
+	}
+	if len(refs) > 0 {
+		cli.base = refs[0]
+	}
+	if len(refs) > 1 {
+		cli.head = refs[1]
+	}
+	return cli
+}
+
+func (c compareCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	base := c.base
+	if base == "" {
+		// git diff base...head compares head with the merge base of the upstream by default.
+		// The branch may not have the upstream.
+		upstream := ""
+		if out, err := runGitCommandOutput(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); err == nil {
+			upstream = strings.TrimSpace(string(out))
+		}
+		var err error
+		base, err = pickRef(ctx, ioErr, "base", upstream)
+		if err != nil {
+			return err
+		}
+		if base == "" {
+			return nil
+		}
+	}
+	head := c.head
+	if head == "" {
+		var err error
+		head, err = pickRef(ctx, ioErr, "head", "HEAD")
+		if err != nil {
+			return err
+		}
+		if head == "" {
+			return nil
+		}
+	}
+
+	// The refs are swapped by the key
+	for {
+		header, err := compareHeader(ctx, base, head)
+		if err != nil {
+			return err
+		}
+		cli, err := newDiffCli([]string{base + "..." + head}, c.fzfQuery)
+		if err != nil {
+			return err
+		}
+		cli.fzfOption = fmt.Sprintf("%s --header %s", cli.fzfOption, shellQuote(header))
+		cli.expectKeys = append(cli.expectKeys, compareKeySwap)
+		key, err := cli.run(ctx, ioIn, ioOut, ioErr)
+		if err != nil {
+			return err
+		}
+		if key != compareKeySwap {
+			return nil
+		}
+		base, head = head, base
+	}
+}
+
+// compareHeader returns the header of the diff between base...head with ahead and behind counts
+func compareHeader(ctx context.Context, base string, head string) (string, error) {
+	// The output is like <commits only in base><tab><commits only in head>
+	out, err := runGitCommandOutput(ctx, "rev-list", "--left-right", "--count", base+"..."+head)
+	if err != nil {
+		return "", fmt.Errorf("failed to count commits between %s and %s: %w", base, head, err)
+	}
+	counts := strings.Fields(string(out))
+	if len(counts) != 2 {
+		return "", fmt.Errorf("unexpected output of git rev-list: %s", out)
+	}
+	return fmt.Sprintf("%s: %s ahead, %s behind %s (%s: swap)", head, counts[1], counts[0], base, compareKeySwap), nil
+}
@@ -0,0 +1,210 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompareSubcommand(t *testing.T) {
+	assert.NotNil(t, NewCompareSubcommand())
+}
+
+func TestNewCompareCommand(t *testing.T) {
+	assert.Equal(t, &compareCli{fzfQuery: "main"}, newCompareCli([]string{}, "main"))
+	assert.Equal(t, &compareCli{base: "origin/master"}, newCompareCli([]string{"origin/master"}, ""))
+	assert.Equal(t, &compareCli{base: "origin/master", head: "feature"}, newCompareCli([]string{"origin/master", "feature"}, ""))
+}
+
+func TestCompareCli_Run(t *testing.T) {
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	refFzfCommand := func(prompt string) string {
+		return fmt.Sprintf("fzf --multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --delimiter '\\t' --with-nth 1,2,4 --prompt '%s> '", "git log --color --oneline --graph -30 {3}", defaultFzfBindOption, prompt)
+	}
+	diffFzfCommand := func(base string, head string, header string) string {
+		return fmt.Sprintf("git diff --color --name-status %s...%s | fzf --multi --ansi --inline-info --layout reverse --preview 'git diff --color %s...%s {2}' --preview-window down:70%% --bind %s --header '%s' --expect alt-h,alt-s", base, head, base, head, defaultFzfBindOption, header)
+	}
+	hunkFzfCommand := fmt.Sprintf("fzf --multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 3.. --expect alt-b", `'\''git-fzf'\'' diff --hunk {1} --hunk-path {2} -- '\''origin/master...feature'\''`, defaultFzfBindOption)
+
+	testCases := []struct {
+		name            string
+		sut             compareCli
+		upstreamErr     error
+		fzfOutputs      []string
+		fzfErr          error
+		wantErr         error
+		wantFzfCommands []string
+		wantFzfInputs   []string
+		wantIO          string
+	}{
+		{
+			name: "pick refs",
+			sut:  compareCli{},
+			fzfOutputs: []string{
+				"remote\torigin/master\trefs/remotes/origin/master\tFix bug\n",
+				"head\tHEAD\tHEAD\tAdd feature\n",
+				"\nM\tmain.go\nA\tREADME.md\n",
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				refFzfCommand("base"),
+				refFzfCommand("head"),
+				diffFzfCommand("origin/master", "HEAD", "HEAD: 2 ahead, 1 behind origin/master (alt-s: swap)"),
+			},
+			wantFzfInputs: []string{
+				"remote\torigin/master\trefs/remotes/origin/master\tFix bug\nhead\tHEAD\tHEAD\tAdd feature\nbranch\tmaster\trefs/heads/master\tFix bug\ntag\tv1.0\trefs/tags/v1.0\tRelease\n",
+				"head\tHEAD\tHEAD\tAdd feature\nbranch\tmaster\trefs/heads/master\tFix bug\nremote\torigin/master\trefs/remotes/origin/master\tFix bug\ntag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			},
+			wantIO: "main.go\nREADME.md\n",
+		},
+		{
+			name:        "no upstream",
+			sut:         compareCli{head: "feature"},
+			upstreamErr: errors.New("no upstream"),
+			fzfOutputs: []string{
+				"branch\tmaster\trefs/heads/master\tFix bug\n",
+				"\nM\tmain.go\n",
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				refFzfCommand("base"),
+				diffFzfCommand("master", "feature", "feature: 2 ahead, 1 behind master (alt-s: swap)"),
+			},
+			wantFzfInputs: []string{
+				"head\tHEAD\tHEAD\tAdd feature\nbranch\tmaster\trefs/heads/master\tFix bug\nremote\torigin/master\trefs/remotes/origin/master\tFix bug\ntag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			},
+			wantIO: "main.go\n",
+		},
+		{
+			name: "swap refs",
+			sut:  compareCli{base: "origin/master", head: "feature"},
+			fzfOutputs: []string{
+				"alt-s\n",
+				"\nM\tmain.go\n",
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				diffFzfCommand("origin/master", "feature", "feature: 2 ahead, 1 behind origin/master (alt-s: swap)"),
+				diffFzfCommand("feature", "origin/master", "origin/master: 2 ahead, 1 behind feature (alt-s: swap)"),
+			},
+			wantIO: "main.go\n",
+		},
+		{
+			name: "select hunks",
+			sut:  compareCli{base: "origin/master", head: "feature"},
+			fzfOutputs: []string{
+				"alt-h\nM\tmain.go\n",
+				"\n1\tmain.go\tmain.go:3\t@@ -3 +3 @@\n",
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				diffFzfCommand("origin/master", "feature", "feature: 2 ahead, 1 behind origin/master (alt-s: swap)"),
+				hunkFzfCommand,
+			},
+			wantFzfInputs: []string{
+				"1\tmain.go\tmain.go:3\t@@ -3 +3 @@\n",
+			},
+			wantIO: "main.go:3\n",
+		},
+		{
+			name: "picking a ref is canceled",
+			sut:  compareCli{},
+			fzfErr: func() error {
+				cmd := exec.Command("sh", "-c", "exit 130")
+				return cmd.Run()
+			}(),
+			wantErr: nil,
+			wantFzfCommands: []string{
+				refFzfCommand("base"),
+			},
+			wantFzfInputs: []string{
+				"remote\torigin/master\trefs/remotes/origin/master\tFix bug\nhead\tHEAD\tHEAD\tAdd feature\nbranch\tmaster\trefs/heads/master\tFix bug\ntag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			},
+		},
+		{
+			name:    "command with fzf error",
+			sut:     compareCli{base: "origin/master", head: "feature"},
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+			wantFzfCommands: []string{
+				diffFzfCommand("origin/master", "feature", "feature: 2 ahead, 1 behind origin/master (alt-s: swap)"),
+			},
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			sut:     compareCli{base: "origin/master", head: "feature"},
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+			wantFzfCommands: []string{
+				diffFzfCommand("origin/master", "feature", "feature: 2 ahead, 1 behind origin/master (alt-s: swap)"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				command := strings.Join(args, " ")
+				switch {
+				case command == "rev-parse --abbrev-ref --symbolic-full-name @{upstream}":
+					if tc.upstreamErr != nil {
+						return nil, tc.upstreamErr
+					}
+					return []byte("origin/master\n"), nil
+				case command == "log -1 --format=%s HEAD":
+					return []byte("Add feature\n"), nil
+				case strings.HasPrefix(command, "log -1 --format=%s "):
+					return nil, errors.New("unknown revision")
+				case command == "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/heads":
+					return []byte("master\trefs/heads/master\tFix bug\n"), nil
+				case command == "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/remotes":
+					return []byte("origin/master\trefs/remotes/origin/master\tFix bug\n"), nil
+				case command == "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/tags":
+					return []byte("v1.0\trefs/tags/v1.0\tRelease\n"), nil
+				case strings.HasPrefix(command, "rev-list --left-right --count "):
+					return []byte("1\t2\n"), nil
+				case command == "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				case command == "rev-parse --show-prefix":
+					return []byte("\n"), nil
+				case command == "-C /repo diff --no-color origin/master...feature -- main.go":
+					return []byte("diff --git a/main.go b/main.go\n@@ -3 +3 @@\n-a\n+b\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			var gotFzfCommands []string
+			var gotFzfInputs []string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				gotFzfCommands = append(gotFzfCommands, commandLine)
+				if strings.HasPrefix(commandLine, "fzf ") {
+					list, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					gotFzfInputs = append(gotFzfInputs, string(list))
+				}
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				require.True(t, len(gotFzfCommands) <= len(tc.fzfOutputs))
+				return []byte(tc.fzfOutputs[len(gotFzfCommands)-1]), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantFzfCommands, gotFzfCommands)
+			assert.Equal(t, tc.wantFzfInputs, gotFzfInputs)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type configCli struct {
+	fzfOption string
+}
+
+// configEntry is an entry of git config --list
+type configEntry struct {
+	scope  string
+	origin string
+	key    string
+	value  string
+}
+
+const (
+	configKeyEdit  = "alt-e"
+	configKeyUnset = "alt-u"
+
+	// The scope of configurations given by git -c or environment variables, which can't be changed
+	configScopeCommand = "command"
+
+	// The prefix of an origin of git config --show-origin which is a file
+	configOriginFilePrefix = "file:"
+)
+
+var (
+	// Scopes which can be set by git config --<scope>
+	configScopes = []string{"local", "global", "system", "worktree"}
+
+	// The overstrike of man like "b\bb" for a bold character
+	manOverstrikeRegexp = regexp.MustCompile(".\x08")
+	// The placeholder in a variable of the man page like <name> of remote.<name>.url
+	manPlaceholderRegexp = regexp.MustCompile(`<[^>]*>`)
+)
+
+func NewConfigSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "git config with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			previewKey, err := flags.GetString("preview")
+			if err != nil {
+				return err
+			}
+			if previewKey != "" {
+				return previewConfig(context.Background(), os.Stdout, previewKey)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newConfigCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("preview", "", "Only print the values of the key in all scopes and the document of the key")
+	_ = flags.MarkHidden("preview")
+	return command
+}
+
+func newConfigCli(fzfQuery string) (*configCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	previewCommand := shellQuoteInFzfOption(self) + " config --preview {2}"
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --with-nth 2.. --expect %s,%s", fzfOption, configKeyEdit, configKeyUnset)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &configCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c configCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// The list is reloaded after each action
+	for {
+		entries, err := listConfigEntries(ctx)
+		if err != nil {
+			return err
+		}
+		// The index is used to get the value as it is, because a value may have a new line
+		var list bytes.Buffer
+		for i, entry := range entries {
+			fmt.Fprintf(&list, "%d\t%s\t%s\t%s\t%s\n", i, entry.key, strings.ReplaceAll(entry.value, "\n", "\\n"), entry.scope, entry.origin)
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		var selected []configEntry
+		for _, line := range lines {
+			index, err := strconv.Atoi(strings.SplitN(line, "\t", 2)[0])
+			if err != nil || index < 0 || len(entries) <= index {
+				return fmt.Errorf("unexpected selection: %s", line)
+			}
+			selected = append(selected, entries[index])
+		}
+		if len(selected) == 0 {
+			return nil
+		}
+
+		switch key {
+		case configKeyEdit:
+			for _, entry := range selected {
+				if err := editConfig(ctx, ioIn, ioErr, entry); err != nil {
+					return err
+				}
+			}
+		case configKeyUnset:
+			if err := unsetConfig(ctx, ioIn, ioErr, selected); err != nil {
+				return err
+			}
+		default:
+			var result strings.Builder
+			for _, entry := range selected {
+				result.WriteString(entry.value + "\n")
+			}
+			if _, err := io.WriteString(ioOut, result.String()); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// editConfig sets a value of the key at a scope which a user inputs
+func editConfig(ctx context.Context, ioIn io.Reader, ioErr io.Writer, entry configEntry) error {
+	defaultScope := entry.scope
+	if defaultScope == configScopeCommand {
+		defaultScope = "local"
+	}
+	scope, err := promptInput(ioIn, ioErr, fmt.Sprintf("Scope of %s (%s)", entry.key, strings.Join(configScopes, ", ")), defaultScope)
+	if err != nil {
+		return err
+	}
+	if !isConfigScope(scope) {
+		return fmt.Errorf("unknown scope %s: it must be one of %s", scope, strings.Join(configScopes, ", "))
+	}
+	value, err := promptInput(ioIn, ioErr, fmt.Sprintf("Value of %s", entry.key), entry.value)
+	if err != nil {
+		return err
+	}
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "config", "--"+scope, entry.key, value); err != nil {
+		return fmt.Errorf("failed to set %s: %w", entry.key, err)
+	}
+	return nil
+}
+
+func isConfigScope(scope string) bool {
+	for _, s := range configScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unsetConfig removes entries from the files where they're defined after a confirmation.
+// The file of an origin is used instead of the scope, because an entry may be in a file of include.path.
+func unsetConfig(ctx context.Context, ioIn io.Reader, ioErr io.Writer, entries []configEntry) error {
+	var summary strings.Builder
+	for _, entry := range entries {
+		if entry.scope == configScopeCommand {
+			return fmt.Errorf("%s is given by a command line or an environment variable and can't be unset", entry.key)
+		}
+		if !strings.HasPrefix(entry.origin, configOriginFilePrefix) {
+			return fmt.Errorf("%s is given by %s and can't be unset", entry.key, entry.origin)
+		}
+		fmt.Fprintf(&summary, "  %s=%s (%s)\n", entry.key, entry.value, entry.scope)
+	}
+	if _, err := fmt.Fprintf(ioErr, "The following entries will be unset:\n%s", summary.String()); err != nil {
+		return fmt.Errorf("failed to output the summary: %w", err)
+	}
+	ok, err := confirm(ioIn, ioErr, "Unset them?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	for _, entry := range entries {
+		// Only the selected value is removed from a multi-valued key
+		file := strings.TrimPrefix(entry.origin, configOriginFilePrefix)
+		args := []string{"config", "--file", file, "--fixed-value", "--unset-all", entry.key, entry.value}
+		// A relative path like .git/config is relative to the top directory
+		if !filepath.IsAbs(file) {
+			top, err := gitTopLevel(ctx)
+			if err != nil {
+				return err
+			}
+			args = append([]string{"-C", top}, args...)
+		}
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+			return fmt.Errorf("failed to unset %s: %w", entry.key, err)
+		}
+	}
+	return nil
+}
+
+// listConfigEntries lists entries of all scopes in the order of git config --list
+func listConfigEntries(ctx context.Context) ([]configEntry, error) {
+	out, err := runGitCommandOutput(ctx, "config", "--list", "--show-origin", "--show-scope", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configurations: %w", err)
+	}
+	// Each entry is like <scope>\0<origin>\0<key>\n<value>\0, and \n<value> is omitted for a key without a value
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var entries []configEntry
+	for i := 0; i+2 < len(fields); i += 3 {
+		keyValue := strings.SplitN(fields[i+2], "\n", 2)
+		entry := configEntry{
+			scope:  fields[i],
+			origin: fields[i+1],
+			key:    keyValue[0],
+		}
+		if len(keyValue) == 2 {
+			entry.value = keyValue[1]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// previewConfig prints values of a key in all scopes and the document of the key in git help config if it's available
+func previewConfig(ctx context.Context, ioOut io.Writer, key string) error {
+	out, err := runGitCommandOutput(ctx, "config", "--show-scope", "--show-origin", "--get-all", key)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	var preview bytes.Buffer
+	preview.Write(out)
+
+	var manPage bytes.Buffer
+	// The man page is printed without a pager because the output isn't a terminal, and it may not be installed
+	if err := runGitCommand(ctx, nil, &manPage, ioutil.Discard, "help", "--man", "config"); err == nil {
+		if document := configDocument(manPage.String(), key); document != "" {
+			preview.WriteString("\n" + document)
+		}
+	}
+	if _, err := ioOut.Write(preview.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the preview: %w", err)
+	}
+	return nil
+}
+
+// configDocument returns the description of a key in the man page of git-config.
+// Each description starts with a line of variables like "core.editor" or "gc.<pattern>.reflogExpire, gc.reflogExpire",
+// and continues while lines are indented deeper than the variables.
+func configDocument(manPage string, key string) string {
+	lines := strings.Split(manOverstrikeRegexp.ReplaceAllString(manPage, ""), "\n")
+	for i, line := range lines {
+		if !isConfigVariableLine(line, key) {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		end := i + 1
+		for ; end < len(lines); end++ {
+			trimmed := strings.TrimLeft(lines[end], " ")
+			if trimmed != "" && len(lines[end])-len(trimmed) <= indent {
+				break
+			}
+		}
+		return strings.TrimRight(strings.Join(lines[i:end], "\n"), "\n") + "\n"
+	}
+	return ""
+}
+
+func isConfigVariableLine(line string, key string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed == line {
+		return false
+	}
+	for _, variable := range strings.Split(trimmed, ", ") {
+		if strings.Contains(variable, " ") {
+			return false
+		}
+		pattern := manPlaceholderRegexp.ReplaceAllString(regexp.QuoteMeta(variable), ".+")
+		if matched, _ := regexp.MatchString("(?i)^"+pattern+"$", key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigManPage = `   Variables
+       Note that this list is non-comprehensive.
+
+       core.fileMode
+           Tells Git if the executable bit of files in the working tree is to be honored.
+
+           See git-update-index(1).
+
+       core.editor
+           Commands such as commit and tag that let you edit messages by launching an editor use the value of this
+           variable when it is set.
+
+       remote.<name>.url
+           The URL of a remote repository.
+
+   gc.<pattern>.reflogExpire, gc.reflogExpire
+           Records older than this time are removed.
+`
+
+func TestNewConfigSubcommand(t *testing.T) {
+	assert.NotNil(t, NewConfigSubcommand())
+}
+
+func TestNewConfigCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *configCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &configCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-e,alt-u", "'\\''git-fzf'\\'' config --preview {2}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "core",
+			want: &configCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-e,alt-u --query core", "'\\''git-fzf'\\'' config --preview {2}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newConfigCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestConfigCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	config := "global\x00file:/home/user/.gitconfig\x00user.name\nJohn Doe\x00local\x00file:.git/config\x00alias.lg\nlog\n--oneline\x00local\x00file:.git/config\x00core.bare\x00command\x00command line:\x00color.ui\nalways\x00"
+	list := "0\tuser.name\tJohn Doe\tglobal\tfile:/home/user/.gitconfig\n1\talias.lg\tlog\\n--oneline\tlocal\tfile:.git/config\n2\tcore.bare\t\tlocal\tfile:.git/config\n3\tcolor.ui\talways\tcommand\tcommand line:\n"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		ioIn            string
+		wantErr         error
+		wantGitCommands []string
+		wantIO          string
+		wantIOErr       string
+	}{
+		{
+			name: "copy values",
+			fzfOutputs: []string{
+				"\n0\tuser.name\tJohn Doe\tglobal\tfile:/home/user/.gitconfig\n1\talias.lg\tlog\\n--oneline\tlocal\tfile:.git/config\n",
+			},
+			wantErr: nil,
+			wantIO:  "John Doe\nlog\n--oneline\n",
+		},
+		{
+			name: "edit a value at another scope",
+			fzfOutputs: []string{
+				"alt-e\n0\tuser.name\tJohn Doe\tglobal\tfile:/home/user/.gitconfig\n",
+				"\n",
+			},
+			ioIn:    "local\nJane Doe\n",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"config --local user.name Jane Doe",
+			},
+			wantIOErr: "Scope of user.name (local, global, system, worktree) [global]: Value of user.name [John Doe]: ",
+		},
+		{
+			name: "edit a value given by a command line",
+			fzfOutputs: []string{
+				"alt-e\n3\tcolor.ui\talways\tcommand\tcommand line:\n",
+				"\n",
+			},
+			ioIn:    "\nauto\n",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"config --local color.ui auto",
+			},
+			wantIOErr: "Scope of color.ui (local, global, system, worktree) [local]: Value of color.ui [always]: ",
+		},
+		{
+			name: "unset values",
+			fzfOutputs: []string{
+				"alt-u\n0\tuser.name\tJohn Doe\tglobal\tfile:/home/user/.gitconfig\n2\tcore.bare\t\tlocal\tfile:.git/config\n",
+				"\n",
+			},
+			ioIn:    "y\n",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"config --file /home/user/.gitconfig --fixed-value --unset-all user.name John Doe",
+				"-C /repo config --file .git/config --fixed-value --unset-all core.bare ",
+			},
+			wantIOErr: "The following entries will be unset:\n  user.name=John Doe (global)\n  core.bare= (local)\nUnset them? (y/N): ",
+		},
+		{
+			name: "edit a value at an unknown scope",
+			fzfOutputs: []string{
+				"alt-e\n0\tuser.name\tJohn Doe\tglobal\tfile:/home/user/.gitconfig\n",
+			},
+			ioIn:      "--file=/tmp/config\nJane Doe\n",
+			wantErr:   errors.New("unknown scope --file=/tmp/config: it must be one of local, global, system, worktree"),
+			wantIOErr: "Scope of user.name (local, global, system, worktree) [global]: ",
+		},
+		{
+			name: "unset a value given by a command line",
+			fzfOutputs: []string{
+				"alt-u\n3\tcolor.ui\talways\tcommand\tcommand line:\n",
+			},
+			wantErr: errors.New("color.ui is given by a command line or an environment variable and can't be unset"),
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := configCli{
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "config --list --show-origin --show-scope -z":
+					return []byte(config), nil
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				gotList, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, list, string(gotList))
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			if tc.fzfErr != nil {
+				assert.True(t, errors.Is(gotErr, tc.wantErr))
+			} else {
+				assert.Equal(t, tc.wantErr, gotErr)
+			}
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestPreviewConfig(t *testing.T) {
+	testCases := []struct {
+		name    string
+		manPage string
+		manErr  error
+		want    string
+	}{
+		{
+			name:    "with the document",
+			manPage: strings.Replace(testConfigManPage, "core.editor", "c\bco\bor\bre\be.\b.e\bed\bdi\bit\bto\bor\br", 1),
+			want:    "global\tfile:/home/user/.gitconfig\tvim\n\n       core.editor\n           Commands such as commit and tag that let you edit messages by launching an editor use the value of this\n           variable when it is set.\n",
+		},
+		{
+			name:   "man is not available",
+			manErr: errors.New("exit status 127"),
+			want:   "global\tfile:/home/user/.gitconfig\tvim\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, "config --show-scope --show-origin --get-all core.editor", strings.Join(args, " "))
+				return []byte("global\tfile:/home/user/.gitconfig\tvim\n"), nil
+			}
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				assert.Equal(t, "help --man config", strings.Join(args, " "))
+				_, err := io.WriteString(ioOut, tc.manPage)
+				require.NoError(t, err)
+				return tc.manErr
+			}
+
+			var gotIOOut bytes.Buffer
+			assert.NoError(t, previewConfig(context.Background(), &gotIOOut, "core.editor"))
+			assert.Equal(t, tc.want, gotIOOut.String())
+		})
+	}
+}
+
+func TestConfigDocument(t *testing.T) {
+	testCases := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "key with multiple paragraphs",
+			key:  "core.filemode",
+			want: "       core.fileMode\n           Tells Git if the executable bit of files in the working tree is to be honored.\n\n           See git-update-index(1).\n",
+		},
+		{
+			name: "key with a placeholder",
+			key:  "remote.origin.url",
+			want: "       remote.<name>.url\n           The URL of a remote repository.\n",
+		},
+		{
+			name: "one of variables",
+			key:  "gc.reflogexpire",
+			want: "   gc.<pattern>.reflogExpire, gc.reflogExpire\n           Records older than this time are removed.\n",
+		},
+		{
+			name: "unknown key",
+			key:  "alias.lg",
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, configDocument(testConfigManPage, tc.key))
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type conflictsCli struct {
+	fzfOption string
+}
+
+// conflictedFile is an unmerged file, whose path is relative to the top directory
+type conflictedFile struct {
+	path    string
+	regions int
+	// firstLine is the line number of the first conflict marker, or 0 if there is no marker
+	firstLine int
+	// hasOurs and hasTheirs are false if the file is deleted on the side
+	hasOurs   bool
+	hasTheirs bool
+}
+
+const (
+	conflictsKeyOurs      = "alt-o"
+	conflictsKeyTheirs    = "alt-t"
+	conflictsKeyMergetool = "alt-m"
+	conflictsKeyEditor    = "alt-e"
+	conflictsKeyResolved  = "alt-r"
+
+	conflictMarkerStart = "<<<<<<<"
+	conflictMarkerEnd   = ">>>>>>>"
+)
+
+func NewConflictsSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "conflicts",
+		Short: "Resolve unmerged files with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			previewPath, err := flags.GetString("preview")
+			if err != nil {
+				return err
+			}
+			if previewPath != "" {
+				return previewConflicts(context.Background(), os.Stdout, previewPath)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newConflictsCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("preview", "", "Only print the conflict hunks of the file with base, ours and theirs")
+	_ = flags.MarkHidden("preview")
+	return command
+}
+
+func newConflictsCli(fzfQuery string) (*conflictsCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	previewCommand := shellQuoteInFzfOption(self) + " conflicts --preview {1}"
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --expect %s,%s,%s,%s,%s", fzfOption,
+		conflictsKeyOurs, conflictsKeyTheirs, conflictsKeyMergetool, conflictsKeyEditor, conflictsKeyResolved)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &conflictsCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c conflictsCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// Paths of unmerged files are relative to the top directory even in a subdirectory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The list is reloaded after each action
+	for {
+		files, err := listConflictedFiles(ctx, top)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			_, err := fmt.Fprintln(ioErr, "There is no unmerged file")
+			return err
+		}
+		var list bytes.Buffer
+		for _, f := range files {
+			fmt.Fprintln(&list, f.String())
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		paths := make([]string, len(lines))
+		for i, line := range lines {
+			paths[i] = strings.SplitN(line, "\t", 2)[0]
+		}
+
+		switch key {
+		case conflictsKeyOurs, conflictsKeyTheirs:
+			if err := takeConflictSide(ctx, ioIn, ioErr, top, files, paths, key == conflictsKeyOurs); err != nil {
+				return err
+			}
+		case conflictsKeyMergetool:
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "mergetool", "--"}, paths...)...); err != nil {
+				return fmt.Errorf("failed to run git mergetool: %w", err)
+			}
+		case conflictsKeyEditor:
+			for _, f := range files {
+				if f.path != paths[0] {
+					continue
+				}
+				line := f.firstLine
+				if line == 0 {
+					line = 1
+				}
+				if err := openEditor(ctx, ioIn, ioErr, filepath.Join(top, f.path), line); err != nil {
+					return err
+				}
+			}
+		case conflictsKeyResolved:
+			// --all also marks a file deleted in the working tree as resolved
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "add", "--all", "--"}, paths...)...); err != nil {
+				return fmt.Errorf("failed to mark files as resolved: %w", err)
+			}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(paths, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// takeConflictSide resolves files by ours or theirs. A file deleted on the side is removed.
+func takeConflictSide(ctx context.Context, ioIn io.Reader, ioErr io.Writer, top string, files []conflictedFile, paths []string, isOurs bool) error {
+	side := "--theirs"
+	if isOurs {
+		side = "--ours"
+	}
+	var checkoutPaths []string
+	var removedPaths []string
+	for _, path := range paths {
+		for _, f := range files {
+			if f.path != path {
+				continue
+			}
+			if (isOurs && f.hasOurs) || (!isOurs && f.hasTheirs) {
+				checkoutPaths = append(checkoutPaths, path)
+			} else {
+				removedPaths = append(removedPaths, path)
+			}
+		}
+	}
+
+	if len(checkoutPaths) > 0 {
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "checkout", side, "--"}, checkoutPaths...)...); err != nil {
+			return fmt.Errorf("failed to checkout %s: %w", side, err)
+		}
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "add", "--"}, checkoutPaths...)...); err != nil {
+			return fmt.Errorf("failed to mark files as resolved: %w", err)
+		}
+	}
+	if len(removedPaths) > 0 {
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "rm", "--quiet", "--"}, removedPaths...)...); err != nil {
+			return fmt.Errorf("failed to remove files deleted by %s: %w", side, err)
+		}
+	}
+	return nil
+}
+
+// listConflictedFiles lists unmerged files from the stages of the index
+func listConflictedFiles(ctx context.Context, top string) ([]conflictedFile, error) {
+	// Each entry is like <mode> <object> <stage><tab><path>
+	out, err := runGitCommandOutput(ctx, "-C", top, "ls-files", "--unmerged", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmerged files: %w", err)
+	}
+	var files []conflictedFile
+	for _, entry := range strings.Split(string(out), "\x00") {
+		fields := strings.SplitN(entry, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		stage := fields[0][len(fields[0])-1:]
+		path := fields[1]
+		if len(files) == 0 || files[len(files)-1].path != path {
+			f := conflictedFile{path: path}
+			// A file deleted on one side may not exist
+			if content, err := ioutil.ReadFile(filepath.Join(top, path)); err == nil {
+				f.regions, f.firstLine = countConflictRegions(content)
+			}
+			files = append(files, f)
+		}
+		switch stage {
+		case "2":
+			files[len(files)-1].hasOurs = true
+		case "3":
+			files[len(files)-1].hasTheirs = true
+		}
+	}
+	return files, nil
+}
+
+// countConflictRegions returns the number of conflict regions and the line number of the first one
+func countConflictRegions(content []byte) (int, int) {
+	regions := 0
+	firstLine := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for i := 1; scanner.Scan(); i++ {
+		if !strings.HasPrefix(scanner.Text(), conflictMarkerStart) {
+			continue
+		}
+		regions++
+		if firstLine == 0 {
+			firstLine = i
+		}
+	}
+	return regions, firstLine
+}
+
+// previewConflicts prints only conflict hunks merged again from the index stages with the diff3 style,
+// so that the base is shown even if merge.conflictStyle isn't diff3
+func previewConflicts(ctx context.Context, ioOut io.Writer, path string) error {
+	dir, err := ioutil.TempDir("", "git-fzf-conflicts")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The stages are 1: base, 2: ours and 3: theirs, and a missing stage is treated as an empty file
+	stageFiles := make([]string, 3)
+	for i, stage := range []string{"1", "2", "3"} {
+		content, err := runGitCommandOutput(ctx, "show", ":"+stage+":"+path)
+		if err != nil {
+			content = nil
+		}
+		stageFiles[i] = filepath.Join(dir, stage)
+		if err := ioutil.WriteFile(stageFiles[i], content, 0644); err != nil {
+			return fmt.Errorf("failed to write the stage %s of %s: %w", stage, path, err)
+		}
+	}
+
+	// The exit status of git merge-file is the number of conflicts
+	out, err := runGitCommandOutput(ctx, "merge-file", "-p", "--diff3",
+		"-L", "ours", "-L", "base", "-L", "theirs",
+		stageFiles[1], stageFiles[0], stageFiles[2])
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128) {
+		return fmt.Errorf("failed to merge the stages of %s: %w", path, err)
+	}
+
+	if _, err := ioOut.Write(extractConflictHunks(out)); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// extractConflictHunks extracts lines between conflict markers with the number of each hunk
+func extractConflictHunks(merged []byte) []byte {
+	var buf bytes.Buffer
+	hunks := 0
+	inHunk := false
+	scanner := bufio.NewScanner(bytes.NewReader(merged))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, conflictMarkerStart) {
+			if hunks > 0 {
+				buf.WriteString("\n")
+			}
+			hunks++
+			fmt.Fprintf(&buf, "@@ conflict %d @@\n", hunks)
+			inHunk = true
+		}
+		if inHunk {
+			buf.WriteString(line + "\n")
+		}
+		if strings.HasPrefix(line, conflictMarkerEnd) {
+			inHunk = false
+		}
+	}
+	return buf.Bytes()
+}
+
+func (f conflictedFile) String() string {
+	return fmt.Sprintf("%s\t%d conflicts", f.path, f.regions)
+}
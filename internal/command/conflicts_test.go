@@ -0,0 +1,322 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConflictsSubcommand(t *testing.T) {
+	assert.NotNil(t, NewConflictsSubcommand())
+}
+
+func TestNewConflictsCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *conflictsCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &conflictsCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-o,alt-t,alt-m,alt-e,alt-r", "'\\''git-fzf'\\'' conflicts --preview {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "main",
+			want: &conflictsCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-o,alt-t,alt-m,alt-e,alt-r --query main", "'\\''git-fzf'\\'' conflicts --preview {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newConflictsCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestConflictsCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name       string
+		fzfOutputs []string
+		// unmergedAfterAction is the output of git ls-files after the first action
+		unmergedAfterAction string
+		fzfErr              error
+		wantErr             error
+		wantGitCommands     []string
+		wantShellCommand    string
+		wantIO              string
+		wantIOErr           string
+	}{
+		{
+			name: "select files",
+			fzfOutputs: []string{
+				"\nREADME.md\t2 conflicts\nmain.go\t0 conflicts\n",
+			},
+			wantErr: nil,
+			wantIO:  "README.md\nmain.go\n",
+		},
+		{
+			name: "take ours",
+			fzfOutputs: []string{
+				"alt-o\nREADME.md\t2 conflicts\n",
+				"\nmain.go\t0 conflicts\n",
+			},
+			unmergedAfterAction: "100644 ccc 2\tmain.go\x00100644 ddd 3\tmain.go\x00",
+			wantErr:             nil,
+			wantGitCommands: []string{
+				"-C {top} checkout --ours -- README.md",
+				"-C {top} add -- README.md",
+			},
+			wantIO: "main.go\n",
+		},
+		{
+			name: "take theirs",
+			fzfOutputs: []string{
+				"alt-t\nREADME.md\t2 conflicts\nmain.go\t0 conflicts\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C {top} checkout --theirs -- main.go",
+				"-C {top} add -- main.go",
+				"-C {top} rm --quiet -- README.md",
+			},
+			wantIOErr: "There is no unmerged file\n",
+		},
+		{
+			name: "mergetool",
+			fzfOutputs: []string{
+				"alt-m\nmain.go\t0 conflicts\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C {top} mergetool -- main.go",
+			},
+			wantIOErr: "There is no unmerged file\n",
+		},
+		{
+			name: "editor",
+			fzfOutputs: []string{
+				"alt-e\nmain.go\t0 conflicts\n",
+			},
+			wantErr:          nil,
+			wantShellCommand: "${EDITOR:-vi} +1 '{top}/main.go'",
+			wantIOErr:        "There is no unmerged file\n",
+		},
+		{
+			name: "mark resolved",
+			fzfOutputs: []string{
+				"alt-r\nmain.go\t0 conflicts\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C {top} add --all -- main.go",
+			},
+			wantIOErr: "There is no unmerged file\n",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := conflictsCli{
+				fzfOption: fzfOption,
+			}
+			top, err := ioutil.TempDir("", "git-fzf-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(top)
+			require.NoError(t, ioutil.WriteFile(filepath.Join(top, "README.md"), []byte("<<<<<<< ours\na\n=======\nb\n>>>>>>> theirs\nc\n<<<<<<< ours\n"), 0644))
+
+			listCount := 0
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				command := strings.Join(args, " ")
+				if command == "rev-parse --show-toplevel" {
+					return []byte(top + "\n"), nil
+				}
+				assert.Equal(t, "-C "+top+" ls-files --unmerged -z", command)
+				listCount++
+				if listCount == 1 {
+					// README.md is deleted by theirs
+					return []byte("100644 aaa 1\tREADME.md\x00100644 bbb 2\tREADME.md\x00100644 ccc 2\tmain.go\x00100644 ddd 3\tmain.go\x00"), nil
+				}
+				return []byte(tc.unmergedAfterAction), nil
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				if fzfCount == 0 {
+					list, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, "README.md\t2 conflicts\nmain.go\t0 conflicts\n", string(list))
+				}
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+			gotShellCommand := ""
+			runShellCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, commandLine string) error {
+				gotShellCommand = commandLine
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			var wantGitCommands []string
+			for _, command := range tc.wantGitCommands {
+				wantGitCommands = append(wantGitCommands, strings.ReplaceAll(command, "{top}", top))
+			}
+			assert.Equal(t, wantGitCommands, gotGitCommands)
+			assert.Equal(t, strings.ReplaceAll(tc.wantShellCommand, "{top}", top), gotShellCommand)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestCountConflictRegions(t *testing.T) {
+	content := "a\n<<<<<<< HEAD\nb\n=======\nc\n>>>>>>> x\nd\n<<<<<<< HEAD\ne\n=======\nf\n>>>>>>> x\n"
+	gotRegions, gotFirstLine := countConflictRegions([]byte(content))
+	assert.Equal(t, 2, gotRegions)
+	assert.Equal(t, 2, gotFirstLine)
+
+	gotRegions, gotFirstLine = countConflictRegions([]byte("a\nb\n"))
+	assert.Equal(t, 0, gotRegions)
+	assert.Equal(t, 0, gotFirstLine)
+}
+
+func TestExtractConflictHunks(t *testing.T) {
+	merged := "a\n<<<<<<< ours\nY\n||||||| base\nb\n=======\nX\n>>>>>>> theirs\nc\n<<<<<<< ours\nE2\n||||||| base\ne\n=======\nE1\n>>>>>>> theirs\n"
+	want := "@@ conflict 1 @@\n<<<<<<< ours\nY\n||||||| base\nb\n=======\nX\n>>>>>>> theirs\n\n@@ conflict 2 @@\n<<<<<<< ours\nE2\n||||||| base\ne\n=======\nE1\n>>>>>>> theirs\n"
+	assert.Equal(t, want, string(extractConflictHunks([]byte(merged))))
+}
+
+func TestConflictsInSubdirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// The path of the top directory may be different from dir by symbolic links
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	git("init", "-q")
+	write("dir/main.go", "a\n")
+	write("dir/deleted.go", "a\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "theirs")
+	write("dir/main.go", "b\n")
+	git("rm", "-q", "dir/deleted.go")
+	git("commit", "-q", "-a", "-m", "theirs")
+	git("checkout", "-q", "-")
+	write("dir/main.go", "c\n")
+	write("dir/deleted.go", "c\n")
+	git("commit", "-q", "-a", "-m", "ours")
+	out, err := exec.Command("git", "-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "merge", "theirs").CombinedOutput()
+	assert.Error(t, err, string(out))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	require.NoError(t, os.Chdir(filepath.Join(dir, "dir")))
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "git", args...).Output()
+	}
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		return exec.CommandContext(ctx, "git", args...).Run()
+	}
+
+	ctx := context.Background()
+	top, err := gitTopLevel(ctx)
+	require.NoError(t, err)
+	files, err := listConflictedFiles(ctx, top)
+	require.NoError(t, err)
+	assert.Equal(t, []conflictedFile{
+		{path: "dir/deleted.go", hasOurs: true},
+		{path: "dir/main.go", regions: 1, firstLine: 1, hasOurs: true, hasTheirs: true},
+	}, files)
+
+	require.NoError(t, takeConflictSide(ctx, nil, ioutil.Discard, top, files, []string{"dir/deleted.go", "dir/main.go"}, false))
+	files, err = listConflictedFiles(ctx, top)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	_, err = os.Stat(filepath.Join(top, "dir/deleted.go"))
+	assert.True(t, os.IsNotExist(err))
+	content, err := ioutil.ReadFile(filepath.Join(top, "dir/main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(content))
+}
@@ -1,11 +1,11 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -14,19 +14,37 @@ import (
 type diffCli struct {
 	listOptions []string
 	fzfOption   string
+	// Keys expected by fzf for files. Keys other than the one of hunks are returned to a caller of run.
+	expectKeys    []string
+	hunkFzfOption string
 }
 
 const (
 	diffFzfPreviewCommand = "git diff --color {{.objectRange}} {{.path}}"
+
+	diffKeyHunks = "alt-h"
+	diffKeyBack  = "alt-b"
 )
 
 func NewDiffSubcommand() *cobra.Command {
-	return &cobra.Command{
+	command := &cobra.Command{
 		Use:   "diff [<commit>[..<commit>]] [-- <git options>]",
 		Short: "git diff with fzf",
 		Args:  cobra.MaximumNArgs(100),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags := cmd.Flags()
+			hunk, err := flags.GetInt("hunk")
+			if err != nil {
+				return err
+			}
+			if hunk > 0 {
+				hunkPath, err := flags.GetString("hunk-path")
+				if err != nil {
+					return err
+				}
+				return previewDiffHunk(context.Background(), os.Stdout, args, hunkPath, hunk)
+			}
+
 			fzfQuery, err := flags.GetString("query")
 			if err != nil {
 				return err
@@ -42,6 +60,12 @@ func NewDiffSubcommand() *cobra.Command {
 			return nil
 		},
 	}
+	flags := command.Flags()
+	flags.Int("hunk", 0, "Only print the hunk at this index, starting from 1, of the file given by --hunk-path")
+	flags.String("hunk-path", "", "The file of the hunk printed by --hunk")
+	_ = flags.MarkHidden("hunk")
+	_ = flags.MarkHidden("hunk-path")
+	return command
 }
 
 func newDiffCli(gitOptions []string, fzfQuery string) (*diffCli, error) {
@@ -66,27 +90,125 @@ func newDiffCli(gitOptions []string, fzfQuery string) (*diffCli, error) {
 		fzfOption = fzfOption + " --query " + fzfQuery
 	}
 
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	// The options of the list are passed after -- not to be parsed as flags of this command
+	hunkPreviewCommand := fmt.Sprintf("%s diff --hunk {1} --hunk-path {2}", shellQuoteInFzfOption(self))
+	if len(gitOptions) > 0 {
+		quotedOptions := make([]string, len(gitOptions))
+		for i, option := range gitOptions {
+			quotedOptions[i] = shellQuoteInFzfOption(option)
+		}
+		hunkPreviewCommand = hunkPreviewCommand + " -- " + strings.Join(quotedOptions, " ")
+	}
+	hunkFzfOption, err := getFzfOption(hunkPreviewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	hunkFzfOption = fmt.Sprintf("%s --delimiter '\\t' --with-nth 3.. --expect %s", hunkFzfOption, diffKeyBack)
+
 	return &diffCli{
-		listOptions: gitOptions,
-		fzfOption:   fzfOption,
+		listOptions:   gitOptions,
+		fzfOption:     fzfOption,
+		expectKeys:    []string{diffKeyHunks},
+		hunkFzfOption: hunkFzfOption,
 	}, nil
 }
 
 func (c diffCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	_, err := c.run(ctx, ioIn, ioOut, ioErr)
+	return err
+}
+
+// run runs fzf for files and hunks until they're selected, or returns a key of expectKeys other than the one of hunks
+func (c diffCli) run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) (string, error) {
 	command := fmt.Sprintf("git diff --color --name-status %s | fzf %s", strings.Join(c.listOptions, " "), c.fzfOption)
-	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// Script canceled by Ctrl-c
-			// Only for bash?: http://tldp.org/LDP/abs/html/exitcodes.html
-			if exitErr.ExitCode() == 130 {
-				return nil
+	if len(c.expectKeys) > 0 {
+		command = command + " --expect " + strings.Join(c.expectKeys, ",")
+	}
+	for {
+		out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return "", nil
 			}
+			return "", fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		if key != "" && key != diffKeyHunks {
+			return key, nil
+		}
+		if len(lines) == 0 {
+			return "", nil
+		}
+		if key != diffKeyHunks {
+			return "", writeFzfResult(ioOut, []byte(strings.Join(lines, "\n")), 1)
+		}
+
+		paths := make([]string, len(lines))
+		for i, line := range lines {
+			// The last field is the new path of a renamed file
+			fields := strings.Split(line, "\t")
+			paths[i] = fields[len(fields)-1]
+		}
+		isBack, err := c.selectHunks(ctx, paths, ioOut, ioErr)
+		if err != nil {
+			return "", err
+		}
+		if !isBack {
+			return "", nil
+		}
+	}
+}
+
+// selectHunks runs fzf for hunks of files and prints <path>:<line> of selected hunks.
+// true is returned if a user goes back to the list of files.
+func (c diffCli) selectHunks(ctx context.Context, paths []string, ioOut io.Writer, ioErr io.Writer) (bool, error) {
+	// Paths of git diff are relative to the top directory, but the printed paths are relative to the current directory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return false, err
+	}
+	prefix, err := runGitCommandOutput(ctx, "rev-parse", "--show-prefix")
+	if err != nil {
+		return false, fmt.Errorf("failed to get the current directory in the repository: %w", err)
+	}
+	var list bytes.Buffer
+	for _, path := range paths {
+		if err := listDiffHunks(ctx, &list, top, strings.TrimSpace(string(prefix)), c.listOptions, path); err != nil {
+			return false, err
+		}
+	}
+	if list.Len() == 0 {
+		_, err := fmt.Fprintln(ioErr, "There is no hunk in the selected files")
+		return true, err
+	}
+
+	command := fmt.Sprintf("fzf %s", c.hunkFzfOption)
+	out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	key, lines := splitFzfExpectResult(out)
+	if key == diffKeyBack {
+		return true, nil
+	}
+
+	var result strings.Builder
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
 		}
-		return fmt.Errorf("failed to run the command %s: %w", command, err)
+		result.WriteString(fields[2] + "\n")
 	}
-	if err := writeFzfResult(ioOut, out, 1); err != nil {
-		return err
+	if _, err := io.WriteString(ioOut, result.String()); err != nil {
+		return false, fmt.Errorf("failed to output the result: %w", err)
 	}
-	return nil
+	return false, nil
 }
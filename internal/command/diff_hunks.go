@@ -0,0 +1,101 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diffHunk is a hunk of git diff for a file
+type diffHunk struct {
+	// header is the line of @@ without colors, which includes the function context
+	header string
+	// line is the first line number of the hunk in the new file
+	line  int
+	lines []string
+}
+
+var (
+	ansiEscapeRegexp     = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	diffHunkHeaderRegexp = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+)
+
+// splitDiffHunks splits the output of git diff for a file into hunks. The output may be colored.
+func splitDiffHunks(out string) []diffHunk {
+	var hunks []diffHunk
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		plainLine := ansiEscapeRegexp.ReplaceAllString(line, "")
+		if matches := diffHunkHeaderRegexp.FindStringSubmatch(plainLine); matches != nil {
+			lineNumber, _ := strconv.Atoi(matches[1])
+			// The line number is 0 if the file is deleted
+			if lineNumber == 0 {
+				lineNumber = 1
+			}
+			hunks = append(hunks, diffHunk{
+				header: plainLine,
+				line:   lineNumber,
+			})
+		}
+		// The lines before the first hunk are the header of the file
+		if len(hunks) > 0 {
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, line)
+		}
+	}
+	return hunks
+}
+
+// diffHunkArgs returns arguments of git diff for a file relative to the top directory with options of the list of files.
+// Paths in the options after -- are ignored because the file is already selected from them.
+func diffHunkArgs(top string, listOptions []string, path string, color string) []string {
+	args := []string{"-C", top, "diff", color}
+	for _, option := range listOptions {
+		if option == "--" {
+			break
+		}
+		args = append(args, option)
+	}
+	return append(args, "--", path)
+}
+
+// listDiffHunks prints hunks of a file like <index><tab><path><tab><relative path>:<line><tab><header>.
+// The path is relative to the top directory, and the relative path is relative to the prefix, which is the current directory.
+func listDiffHunks(ctx context.Context, ioOut io.Writer, top string, prefix string, listOptions []string, path string) error {
+	out, err := runGitCommandOutput(ctx, diffHunkArgs(top, listOptions, path, "--no-color")...)
+	if err != nil {
+		return fmt.Errorf("failed to get the diff of %s: %w", path, err)
+	}
+	relativePath, err := filepath.Rel(filepath.Join(".", prefix), path)
+	if err != nil {
+		return fmt.Errorf("failed to get the relative path of %s: %w", path, err)
+	}
+	for i, hunk := range splitDiffHunks(string(out)) {
+		if _, err := fmt.Fprintf(ioOut, "%d\t%s\t%s:%d\t%s\n", i+1, path, relativePath, hunk.line, hunk.header); err != nil {
+			return fmt.Errorf("failed to output the hunks: %w", err)
+		}
+	}
+	return nil
+}
+
+// previewDiffHunk prints the hunk of a file relative to the top directory at the index, which starts from 1
+func previewDiffHunk(ctx context.Context, ioOut io.Writer, listOptions []string, path string, index int) error {
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := runGitCommandOutput(ctx, diffHunkArgs(top, listOptions, path, "--color")...)
+	if err != nil {
+		return fmt.Errorf("failed to get the diff of %s: %w", path, err)
+	}
+	hunks := splitDiffHunks(string(out))
+	if index < 1 || len(hunks) < index {
+		return fmt.Errorf("%s doesn't have the hunk %d", path, index)
+	}
+	if _, err := io.WriteString(ioOut, strings.Join(hunks[index-1].lines, "\n")+"\n"); err != nil {
+		return fmt.Errorf("failed to output the hunk: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitDiffHunks(t *testing.T) {
+	testCases := []struct {
+		name string
+		out  string
+		want []diffHunk
+	}{
+		{
+			name: "modified file",
+			out:  "diff --git a/main.go b/main.go\nindex 1..2 100644\n--- a/main.go\n+++ b/main.go\n@@ -3,2 +3,3 @@ func main() {\n a\n+b\n c\n@@ -20 +21 @@\n-d\n+e\n",
+			want: []diffHunk{
+				{
+					header: "@@ -3,2 +3,3 @@ func main() {",
+					line:   3,
+					lines:  []string{"@@ -3,2 +3,3 @@ func main() {", " a", "+b", " c"},
+				},
+				{
+					header: "@@ -20 +21 @@",
+					line:   21,
+					lines:  []string{"@@ -20 +21 @@", "-d", "+e"},
+				},
+			},
+		},
+		{
+			name: "colored diff",
+			out:  "\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m func main() {\n\x1b[31m-a\x1b[m\n\x1b[32m+b\x1b[m\n",
+			want: []diffHunk{
+				{
+					header: "@@ -1 +1 @@ func main() {",
+					line:   1,
+					lines:  []string{"\x1b[36m@@ -1 +1 @@\x1b[m func main() {", "\x1b[31m-a\x1b[m", "\x1b[32m+b\x1b[m"},
+				},
+			},
+		},
+		{
+			name: "deleted file",
+			out:  "diff --git a/main.go b/main.go\ndeleted file mode 100644\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+			want: []diffHunk{
+				{
+					header: "@@ -1,2 +0,0 @@",
+					line:   1,
+					lines:  []string{"@@ -1,2 +0,0 @@", "-a", "-b"},
+				},
+			},
+		},
+		{
+			name: "binary file",
+			out:  "diff --git a/image.png b/image.png\nBinary files a/image.png and b/image.png differ\n",
+			want: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, splitDiffHunks(tc.out))
+		})
+	}
+}
+
+func TestDiffHunkArgs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		listOptions []string
+		want        []string
+	}{
+		{
+			name:        "no options",
+			listOptions: []string{},
+			want:        []string{"-C", "/repo", "diff", "--color", "--", "main.go"},
+		},
+		{
+			name:        "options",
+			listOptions: []string{"--cached", "--diff-filter", "M"},
+			want:        []string{"-C", "/repo", "diff", "--color", "--cached", "--diff-filter", "M", "--", "main.go"},
+		},
+		{
+			name:        "paths are ignored",
+			listOptions: []string{"origin/master", "--", "dir"},
+			want:        []string{"-C", "/repo", "diff", "--color", "origin/master", "--", "main.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, diffHunkArgs("/repo", tc.listOptions, "main.go", "--color"))
+		})
+	}
+}
+
+func TestListDiffHunks(t *testing.T) {
+	testCases := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{
+			name:   "top directory",
+			prefix: "",
+			want:   "1\tdir/main file.go\tdir/main file.go:3\t@@ -3,2 +3,3 @@ func main() {\n2\tdir/main file.go\tdir/main file.go:21\t@@ -20 +21 @@\n",
+		},
+		{
+			name:   "directory of the file",
+			prefix: "dir/",
+			want:   "1\tdir/main file.go\tmain file.go:3\t@@ -3,2 +3,3 @@ func main() {\n2\tdir/main file.go\tmain file.go:21\t@@ -20 +21 @@\n",
+		},
+		{
+			name:   "another directory",
+			prefix: "other/sub/",
+			want:   "1\tdir/main file.go\t../../dir/main file.go:3\t@@ -3,2 +3,3 @@ func main() {\n2\tdir/main file.go\t../../dir/main file.go:21\t@@ -20 +21 @@\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, "-C /repo diff --no-color -- dir/main file.go", strings.Join(args, " "))
+				return []byte("diff --git a/main.go b/main.go\n@@ -3,2 +3,3 @@ func main() {\n a\n+b\n c\n@@ -20 +21 @@\n-d\n+e\n"), nil
+			}
+			var gotIOOut bytes.Buffer
+			assert.NoError(t, listDiffHunks(context.Background(), &gotIOOut, "/repo", tc.prefix, []string{}, "dir/main file.go"))
+			assert.Equal(t, tc.want, gotIOOut.String())
+		})
+	}
+}
+
+func TestPreviewDiffHunk(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n@@ -3,2 +3,3 @@ func main() {\n a\n+b\n c\n@@ -20 +21 @@\n-d\n+e\n"
+	gitErr := errors.New("git error")
+
+	testCases := []struct {
+		name    string
+		index   int
+		gitErr  error
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "second hunk",
+			index: 2,
+			want:  "@@ -20 +21 @@\n-d\n+e\n",
+		},
+		{
+			name:    "no hunk",
+			index:   3,
+			wantErr: fmt.Errorf("%s doesn't have the hunk %d", "main.go", 3),
+		},
+		{
+			name:    "git error",
+			index:   1,
+			gitErr:  gitErr,
+			wantErr: fmt.Errorf("failed to get the diff of %s: %w", "main.go", gitErr),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				if strings.Join(args, " ") == "rev-parse --show-toplevel" {
+					return []byte("/repo\n"), nil
+				}
+				assert.Equal(t, "-C /repo diff --color origin/master --diff-filter M -- main.go", strings.Join(args, " "))
+				return []byte(diff), tc.gitErr
+			}
+			var gotIOOut bytes.Buffer
+			gotErr := previewDiffHunk(context.Background(), &gotIOOut, []string{"origin/master", "--diff-filter", "M"}, "main.go", tc.index)
+			assert.Equal(t, tc.wantErr, gotErr)
+			assert.Equal(t, tc.want, gotIOOut.String())
+		})
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -20,6 +22,10 @@ func TestNewDiffSubcommand(t *testing.T) {
 }
 
 func TestNewDiffCommand(t *testing.T) {
+	hunkFzfOption := func(previewCommand string) string {
+		return fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 3.. --expect alt-b", previewCommand, defaultFzfBindOption)
+	}
+
 	testCases := []struct {
 		name       string
 		gitOptions []string
@@ -33,8 +39,10 @@ func TestNewDiffCommand(t *testing.T) {
 			gitOptions: []string{},
 			fzfQuery:   "",
 			want: &diffCli{
-				listOptions: []string{},
-				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s", "git diff --color  {2}", defaultFzfBindOption),
+				listOptions:   []string{},
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s", "git diff --color  {2}", defaultFzfBindOption),
+				expectKeys:    []string{"alt-h"},
+				hunkFzfOption: hunkFzfOption(`'\''git-fzf'\'' diff --hunk {1} --hunk-path {2}`),
 			},
 			wantErr: nil,
 		},
@@ -52,7 +60,9 @@ func TestNewDiffCommand(t *testing.T) {
 					"--diff-filter",
 					"A",
 				},
-				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --query config", "git diff --color origin/master {2}", defaultFzfBindOption),
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --query config", "git diff --color origin/master {2}", defaultFzfBindOption),
+				expectKeys:    []string{"alt-h"},
+				hunkFzfOption: hunkFzfOption(`'\''git-fzf'\'' diff --hunk {1} --hunk-path {2} -- '\''origin/master'\'' '\''--diff-filter'\'' '\''A'\''`),
 			},
 			wantErr: nil,
 		},
@@ -94,7 +104,7 @@ func TestDiffCli_Run(t *testing.T) {
 			"git diff --color --name-status origin/master",
 			fzfOption,
 		), commandLine)
-		return bytes.NewBufferString("M\tREADME.md\nA\tLICENSE").Bytes(), nil
+		return bytes.NewBufferString("\nM\tREADME.md\nA\tLICENSE").Bytes(), nil
 	}
 	defaultWantErr := errors.New("want error")
 	exitErr := exec.ExitError{}
@@ -161,3 +171,148 @@ func TestDiffCli_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffCli_Run_Hunks(t *testing.T) {
+	fzfOption := "--inline-info"
+	hunkFzfOption := "--layout reverse"
+	listCommand := "git diff --color --name-status origin/master --diff-filter M | fzf " + fzfOption + " --expect alt-h"
+	hunks := "1\tmain.go\tmain.go:3\t@@ -3,2 +3,2 @@ func main() {\n2\tmain.go\tmain.go:20\t@@ -20 +20 @@\n"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		diff            string
+		wantFzfCommands []string
+		wantIO          string
+		wantIOErr       string
+	}{
+		{
+			name: "select hunks",
+			fzfOutputs: []string{
+				"alt-h\nM\tmain.go\n",
+				"\n2\tmain.go\tmain.go:20\t@@ -20 +20 @@\n",
+			},
+			diff: "diff --git a/main.go b/main.go\n@@ -3,2 +3,2 @@ func main() {\n-a\n+b\n@@ -20 +20 @@\n-c\n+d\n",
+			wantFzfCommands: []string{
+				listCommand,
+				"fzf " + hunkFzfOption,
+			},
+			wantIO: "main.go:20\n",
+		},
+		{
+			name: "go back to the list of files",
+			fzfOutputs: []string{
+				"alt-h\nM\tmain.go\n",
+				"alt-b\n1\tmain.go\tmain.go:3\t@@ -3,2 +3,2 @@ func main() {\n",
+				"\nM\tmain.go\n",
+			},
+			diff: "diff --git a/main.go b/main.go\n@@ -3,2 +3,2 @@ func main() {\n-a\n+b\n@@ -20 +20 @@\n-c\n+d\n",
+			wantFzfCommands: []string{
+				listCommand,
+				"fzf " + hunkFzfOption,
+				listCommand,
+			},
+			wantIO: "main.go\n",
+		},
+		{
+			name: "no hunk in a binary file",
+			fzfOutputs: []string{
+				"alt-h\nM\tmain.go\n",
+				"",
+			},
+			diff: "diff --git a/main.go b/main.go\nBinary files a/main.go and b/main.go differ\n",
+			wantFzfCommands: []string{
+				listCommand,
+				listCommand,
+			},
+			wantIO:    "",
+			wantIOErr: "There is no hunk in the selected files\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := diffCli{
+				listOptions:   []string{"origin/master", "--diff-filter", "M"},
+				fzfOption:     fzfOption,
+				expectKeys:    []string{diffKeyHunks},
+				hunkFzfOption: hunkFzfOption,
+			}
+
+			var gotFzfCommands []string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				gotFzfCommands = append(gotFzfCommands, commandLine)
+				if commandLine == "fzf "+hunkFzfOption {
+					list, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, hunks, string(list))
+				}
+				require.True(t, len(gotFzfCommands) <= len(tc.fzfOutputs))
+				return []byte(tc.fzfOutputs[len(gotFzfCommands)-1]), nil
+			}
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				case "rev-parse --show-prefix":
+					return []byte("\n"), nil
+				}
+				assert.Equal(t, "-C /repo diff --no-color origin/master --diff-filter M -- main.go", strings.Join(args, " "))
+				return []byte(tc.diff), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			require.NoError(t, sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr))
+			assert.Equal(t, tc.wantFzfCommands, gotFzfCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestDiffHunksInSubdirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	git("init", "-q")
+	write("main.go", "a\n")
+	write("sub/main.go", "a\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	write("main.go", "b\n")
+	write("sub/main.go", "b\n")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	require.NoError(t, os.Chdir(filepath.Join(dir, "sub")))
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "git", args...).Output()
+	}
+	runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+		list, err := ioutil.ReadAll(ioIn)
+		require.NoError(t, err)
+		// All hunks are selected without a key
+		return append([]byte("\n"), list...), nil
+	}
+
+	sut := diffCli{hunkFzfOption: "--inline-info"}
+	var gotIOOut bytes.Buffer
+	isBack, err := sut.selectHunks(context.Background(), []string{"main.go", "sub/main.go"}, &gotIOOut, ioutil.Discard)
+	require.NoError(t, err)
+	assert.False(t, isBack)
+	assert.Equal(t, "../main.go:1\nmain.go:1\n", gotIOOut.String())
+}
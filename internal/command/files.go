@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type filesCli struct {
+	listCommand string
+	fzfOption   string
+}
+
+const (
+	// The preview command isn't built by a template because a quoted revision is escaped
+	filesFzfPreviewCommand = "%s | (bat --color=always --style=numbers --file-name {} 2>/dev/null || cat)"
+	filesFzfBindOption     = "alt-t:reload({{.tracked}}),alt-u:reload({{.untracked}}),alt-i:reload({{.ignored}})"
+)
+
+func NewFilesSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "files [<rev>]",
+		Short: "git ls-files with fzf",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			untracked, err := flags.GetBool("untracked")
+			if err != nil {
+				return err
+			}
+			ignored, err := flags.GetBool("ignored")
+			if err != nil {
+				return err
+			}
+			revision := ""
+			if len(args) > 0 {
+				revision = args[0]
+			}
+
+			cli, err := newFilesCli(revision, untracked, ignored, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.BoolP("untracked", "u", false, "Show untracked files as well")
+	flags.BoolP("ignored", "i", false, "Show ignored files as well")
+	return command
+}
+
+func newFilesCli(revision string, untracked bool, ignored bool, fzfQuery string) (*filesCli, error) {
+	if revision != "" && (untracked || ignored) {
+		return nil, errors.New("untracked or ignored files cannot be shown with a revision")
+	}
+
+	show := "cat {}"
+	if revision != "" {
+		// git ls-tree lists paths relative to the current directory, and so does ./ of git show
+		show = "git show " + shellQuoteInFzfOption(revision+":./") + "{}"
+	}
+	previewCommand := fmt.Sprintf(filesFzfPreviewCommand, show)
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	// Paths are listed as they are without quoting non-ASCII characters by -z
+	fzfOption = fzfOption + " --read0"
+	if revision == "" {
+		// Files in the working tree can be switched while fzf is running
+		bindOption, err := commandFromTemplate("bind", filesFzfBindOption, map[string]interface{}{
+			"tracked":   filesListCommand("", false, false),
+			"untracked": filesListCommand("", true, false),
+			"ignored":   filesListCommand("", true, true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid fzf bind option: %w", err)
+		}
+		fzfOption = fzfOption + " --bind '" + bindOption + "'"
+	}
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &filesCli{
+		listCommand: filesListCommand(revision, untracked, ignored),
+		fzfOption:   fzfOption,
+	}, nil
+}
+
+// filesListCommand returns the command to list files separated by NUL characters
+func filesListCommand(revision string, untracked bool, ignored bool) string {
+	if revision != "" {
+		return "git ls-tree -r -z --name-only " + shellQuote(revision)
+	}
+
+	commands := []string{"git ls-files -z"}
+	if untracked {
+		commands = append(commands, "git ls-files -z --others --exclude-standard")
+	}
+	if ignored {
+		commands = append(commands, "git ls-files -z --others --ignored --exclude-standard")
+	}
+	if len(commands) == 1 {
+		return commands[0]
+	}
+	return "(" + strings.Join(commands, "; ") + ")"
+}
+
+func (c filesCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	command := fmt.Sprintf("%s | fzf %s", c.listCommand, c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	// A path may include spaces
+	if _, err := io.WriteString(ioOut, strings.TrimSpace(string(out))+"\n"); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
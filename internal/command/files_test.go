@@ -0,0 +1,176 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFilesSubcommand(t *testing.T) {
+	assert.NotNil(t, NewFilesSubcommand())
+}
+
+func TestNewFilesCommand(t *testing.T) {
+	bindOption := "alt-t:reload(git ls-files -z),alt-u:reload((git ls-files -z; git ls-files -z --others --exclude-standard)),alt-i:reload((git ls-files -z; git ls-files -z --others --exclude-standard; git ls-files -z --others --ignored --exclude-standard))"
+
+	testCases := []struct {
+		name      string
+		revision  string
+		untracked bool
+		ignored   bool
+		fzfQuery  string
+		envVars   map[string]string
+		want      *filesCli
+		wantErr   error
+	}{
+		{
+			name:     "no options",
+			revision: "",
+			fzfQuery: "",
+			want: &filesCli{
+				listCommand: "git ls-files -z",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --read0 --bind '%s'", "cat {} | (bat --color=always --style=numbers --file-name {} 2>/dev/null || cat)", defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "untracked and ignored files",
+			revision:  "",
+			untracked: true,
+			ignored:   true,
+			fzfQuery:  "config",
+			want: &filesCli{
+				listCommand: "(git ls-files -z; git ls-files -z --others --exclude-standard; git ls-files -z --others --ignored --exclude-standard)",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --read0 --bind '%s' --query config", "cat {} | (bat --color=always --style=numbers --file-name {} 2>/dev/null || cat)", defaultFzfBindOption, bindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "revision",
+			revision: "origin/master",
+			fzfQuery: "config",
+			want: &filesCli{
+				listCommand: "git ls-tree -r -z --name-only 'origin/master'",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --read0 --query config", `git show '\''origin/master:./'\''{} | (bat --color=always --style=numbers --file-name {} 2>/dev/null || cat)`, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "revision with untracked files",
+			revision:  "origin/master",
+			untracked: true,
+			want:      nil,
+			wantErr:   errors.New("untracked or ignored files cannot be shown with a revision"),
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			revision: "",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newFilesCli(tc.revision, tc.untracked, tc.ignored, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestFilesCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultRunCommand := func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+		assert.Equal(t, fmt.Sprintf("%s | fzf %s",
+			"git ls-files",
+			fzfOption,
+		), commandLine)
+		return bytes.NewBufferString("README.md\ndoc/file name.txt\n").Bytes(), nil
+	}
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		sut               filesCli
+		wantErr           error
+		wantIO            string
+		wantIOErr         string
+	}{
+		{
+			name: "path output",
+			sut: filesCli{
+				listCommand: "git ls-files",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: defaultRunCommand,
+			wantErr:           nil,
+			wantIO:            "README.md\ndoc/file name.txt\n",
+			wantIOErr:         "",
+		},
+		{
+			name: "command with fzf error",
+			sut: filesCli{
+				listCommand: "git ls-files",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, defaultWantErr
+			},
+			wantErr:   defaultWantErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: filesCli{
+				listCommand: "git ls-files",
+				fzfOption:   fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, &exitErr
+			},
+			wantErr:   &exitErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
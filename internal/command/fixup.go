@@ -0,0 +1,161 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type fixupCli struct {
+	base       string
+	commitType string
+	rebase     bool
+	fzfOption  string
+}
+
+const (
+	fixupTypeFixup  = "fixup"
+	fixupTypeAmend  = "amend"
+	fixupTypeSquash = "squash"
+)
+
+func NewFixupSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "fixup [<base>]",
+		Short: "git commit --fixup for a commit selected with fzf",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			commitType, err := flags.GetString("type")
+			if err != nil {
+				return err
+			}
+			rebase, err := flags.GetBool("rebase")
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			base := ""
+			if len(args) > 0 {
+				base = args[0]
+			} else {
+				base, err = getFixupBase(ctx)
+				if err != nil {
+					return err
+				}
+			}
+
+			cli, err := newFixupCli(base, commitType, rebase, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(ctx, os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.StringP("type", "t", fixupTypeFixup, "The type of the commit: fixup, amend or squash")
+	flags.BoolP("rebase", "r", false, "Run git rebase --autosquash after the commit")
+	return command
+}
+
+// getFixupBase returns the upstream, or the merge base with the default branch of origin if there is no upstream
+func getFixupBase(ctx context.Context) (string, error) {
+	if _, err := runGitCommandOutput(ctx, "rev-parse", "--verify", "--quiet", "@{upstream}"); err == nil {
+		return "@{upstream}", nil
+	}
+	out, err := runGitCommandOutput(ctx, "merge-base", "HEAD", "origin/HEAD")
+	if err != nil {
+		return "", errors.New("no upstream or origin/HEAD is found. Specify <base>")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func newFixupCli(base string, commitType string, rebase bool, fzfQuery string) (*fixupCli, error) {
+	switch commitType {
+	case fixupTypeFixup, fixupTypeAmend, fixupTypeSquash:
+	default:
+		return nil, fmt.Errorf("invalid type of the commit: %s", commitType)
+	}
+
+	previewCommand, err := commandFromTemplate("preview", logFzfPreviewCommand, map[string]interface{}{
+		"path":        "{1}",
+		"objectRange": "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --no-multi"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &fixupCli{
+		base:       base,
+		commitType: commitType,
+		rebase:     rebase,
+		fzfOption:  fzfOption,
+	}, nil
+}
+
+func (c fixupCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// amend! commit can be created without changes to reword the commit message
+	if c.commitType != fixupTypeAmend {
+		if _, err := runGitCommandOutput(ctx, "diff", "--cached", "--quiet"); err == nil {
+			return errors.New("no changes are staged")
+		}
+	}
+
+	command := fmt.Sprintf("git log --color --oneline %s..HEAD | fzf %s", c.base, c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return nil
+	}
+	commit := fields[0]
+
+	var commitOption string
+	switch c.commitType {
+	case fixupTypeAmend:
+		commitOption = "--fixup=amend:" + commit
+	case fixupTypeSquash:
+		commitOption = "--squash=" + commit
+	default:
+		commitOption = "--fixup=" + commit
+	}
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "commit", commitOption); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	if !c.rebase {
+		return nil
+	}
+
+	// The todo list is not edited by a user
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "-c", "sequence.editor=:", "rebase", "--interactive", "--autosquash", c.base); err != nil {
+		return fmt.Errorf("failed to rebase: %w", err)
+	}
+	return nil
+}
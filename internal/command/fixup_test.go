@@ -0,0 +1,276 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFixupSubcommand(t *testing.T) {
+	assert.NotNil(t, NewFixupSubcommand())
+}
+
+func TestNewFixupCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		base       string
+		commitType string
+		rebase     bool
+		fzfQuery   string
+		envVars    map[string]string
+		want       *fixupCli
+		wantErr    error
+	}{
+		{
+			name:       "no options",
+			base:       "@{upstream}",
+			commitType: "fixup",
+			rebase:     false,
+			fzfQuery:   "",
+			want: &fixupCli{
+				base:       "@{upstream}",
+				commitType: "fixup",
+				rebase:     false,
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "all options",
+			base:       "origin/master",
+			commitType: "squash",
+			rebase:     true,
+			fzfQuery:   "config",
+			want: &fixupCli{
+				base:       "origin/master",
+				commitType: "squash",
+				rebase:     true,
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --query config", "git show --color  {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "invalid type",
+			base:       "origin/master",
+			commitType: "reword",
+			want:       nil,
+			wantErr:    errors.New("invalid type of the commit: reword"),
+		},
+		{
+			name:       "GIT_FZF_FZF_OPTION includes invalid env",
+			base:       "origin/master",
+			commitType: "fixup",
+			fzfQuery:   "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newFixupCli(tc.base, tc.commitType, tc.rebase, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestGetFixupBase(t *testing.T) {
+	testCases := []struct {
+		name       string
+		gitOutputs map[string]string
+		want       string
+		wantIsErr  bool
+	}{
+		{
+			name: "upstream",
+			gitOutputs: map[string]string{
+				"rev-parse --verify --quiet @{upstream}": "abc\n",
+			},
+			want:      "@{upstream}",
+			wantIsErr: false,
+		},
+		{
+			name: "merge base",
+			gitOutputs: map[string]string{
+				"merge-base HEAD origin/HEAD": "xyz\n",
+			},
+			want:      "xyz",
+			wantIsErr: false,
+		},
+		{
+			name:       "no base",
+			gitOutputs: map[string]string{},
+			want:       "",
+			wantIsErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				out, ok := tc.gitOutputs[strings.Join(args, " ")]
+				if !ok {
+					return nil, errors.New("error")
+				}
+				return []byte(out), nil
+			}
+			got, gotErr := getFixupBase(context.Background())
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+		})
+	}
+}
+
+func TestFixupCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultRunCommand := func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+		assert.Equal(t, fmt.Sprintf("%s | fzf %s",
+			"git log --color --oneline origin/master..HEAD",
+			fzfOption,
+		), commandLine)
+		return bytes.NewBufferString("abc Commit message1\n").Bytes(), nil
+	}
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		noStagedChanges   bool
+		sut               fixupCli
+		wantErr           error
+		wantIsErr         bool
+		wantGitCommands   []string
+	}{
+		{
+			name: "fixup",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "fixup",
+				fzfOption:  fzfOption,
+			},
+			runCommandWithFzf: defaultRunCommand,
+			wantGitCommands: []string{
+				"commit --fixup=abc",
+			},
+		},
+		{
+			name: "amend without staged changes and rebase",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "amend",
+				rebase:     true,
+				fzfOption:  fzfOption,
+			},
+			noStagedChanges:   true,
+			runCommandWithFzf: defaultRunCommand,
+			wantGitCommands: []string{
+				"commit --fixup=amend:abc",
+				"-c sequence.editor=: rebase --interactive --autosquash origin/master",
+			},
+		},
+		{
+			name: "squash",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "squash",
+				fzfOption:  fzfOption,
+			},
+			runCommandWithFzf: defaultRunCommand,
+			wantGitCommands: []string{
+				"commit --squash=abc",
+			},
+		},
+		{
+			name: "no staged changes",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "fixup",
+				fzfOption:  fzfOption,
+			},
+			noStagedChanges:   true,
+			runCommandWithFzf: defaultRunCommand,
+			wantIsErr:         true,
+		},
+		{
+			name: "command with fzf error",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "fixup",
+				fzfOption:  fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, defaultWantErr
+			},
+			wantErr:   defaultWantErr,
+			wantIsErr: true,
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: fixupCli{
+				base:       "origin/master",
+				commitType: "fixup",
+				fzfOption:  fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, &exitErr
+			},
+			wantErr:   &exitErr,
+			wantIsErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, []string{"diff", "--cached", "--quiet"}, args)
+				if tc.noStagedChanges {
+					return nil, nil
+				}
+				return nil, &exitErr
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				// The output of git is written to stderr, so that stdout is kept for results like other subcommands
+				_, err := io.WriteString(ioOut, "git output\n")
+				return err
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(gotErr, tc.wantErr))
+			}
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, "", gotIOOut.String())
+			assert.Equal(t, strings.Repeat("git output\n", len(tc.wantGitCommands)), gotIOErr.String())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -16,6 +17,8 @@ const (
 	envNameFzfBindOption = "GIT_FZF_FZF_BIND_OPTION"
 	defaultFzfBindOption = "ctrl-k:kill-line,ctrl-alt-t:toggle-preview,ctrl-alt-n:preview-down,ctrl-alt-p:preview-up,ctrl-alt-v:preview-page-down"
 
+	shortHashLength = 7
+
 	defaultFzfOption = "--multi --ansi --inline-info --layout reverse --preview '$GIT_FZF_FZF_PREVIEW_OPTION' --preview-window down:70% --bind $GIT_FZF_FZF_BIND_OPTION"
 )
 
@@ -26,8 +29,79 @@ var (
 		cmd.Stdin = ioIn
 		return cmd.Output()
 	}
+
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Stdin = ioIn
+		cmd.Stdout = ioOut
+		cmd.Stderr = ioErr
+		return cmd.Run()
+	}
+
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		return cmd.Output()
+	}
+
+	runShellCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, commandLine string) error {
+		cmd := exec.CommandContext(ctx, "sh", "-c", commandLine)
+		cmd.Stdin = ioIn
+		cmd.Stdout = ioOut
+		cmd.Stderr = ioErr
+		return cmd.Run()
+	}
+
+	executablePath = os.Executable
 )
 
+// selfCommand returns the command line to run this binary again, e.g. from fzf's reload action
+func selfCommand() (string, error) {
+	path, err := executablePath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the path of the executable: %w", err)
+	}
+	return path, nil
+}
+
+// gitTopLevel returns the top directory of the working tree, which paths of commands like git status are relative to
+func gitTopLevel(ctx context.Context) (string, error) {
+	out, err := runGitCommandOutput(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the top directory: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// openEditor opens a file by $EDITOR at the line, in the same way as the key binding of git fzf grep
+func openEditor(ctx context.Context, ioIn io.Reader, ioOut io.Writer, path string, line int) error {
+	commandLine := fmt.Sprintf("${EDITOR:-vi} +%d %s", line, shellQuote(path))
+	if err := runShellCommand(ctx, ioIn, ioOut, ioOut, commandLine); err != nil {
+		return fmt.Errorf("failed to open %s by the editor: %w", path, err)
+	}
+	return nil
+}
+
+// isCanceledByUser returns true if fzf was canceled by Ctrl-c or Esc
+func isCanceledByUser(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	// Only for bash?: http://tldp.org/LDP/abs/html/exitcodes.html
+	return exitErr.ExitCode() == 130
+}
+
+// shellQuote quotes a string as a single argument of sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteInFzfOption quotes a string as a single argument of a command in fzf options like --preview,
+// which is quoted by single quotes again in the fzf option
+func shellQuoteInFzfOption(s string) string {
+	return strings.ReplaceAll(shellQuote(s), "'", `'\''`)
+}
+
 func commandFromTemplate(name string, command string, data map[string]interface{}) (string, error) {
 	tmpl, err := template.New(name).Option("missingkey=error").Parse(command)
 	if err != nil {
@@ -71,6 +145,64 @@ func getFzfOption(previewCommand string) (string, error) {
 	return fzfOption, nil
 }
 
+// splitFzfExpectResult splits the output of fzf with --expect option into the pressed key and selected lines.
+// The key is empty if the enter key is pressed.
+func splitFzfExpectResult(out []byte) (string, []string) {
+	lineSeparator := "\n"
+	lines := strings.Split(strings.TrimRight(string(out), lineSeparator), lineSeparator)
+	var selected []string
+	for _, line := range lines[1:] {
+		if line != "" {
+			selected = append(selected, line)
+		}
+	}
+	return lines[0], selected
+}
+
+// promptInput asks a user to input a line. The default value is used if nothing is input.
+func promptInput(ioIn io.Reader, ioErr io.Writer, message string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		message = fmt.Sprintf("%s [%s]", message, defaultValue)
+	}
+	if _, err := fmt.Fprintf(ioErr, "%s: ", message); err != nil {
+		return "", fmt.Errorf("failed to output the prompt: %w", err)
+	}
+
+	// Read byte by byte not to consume the input after the line
+	var builder strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := ioIn.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			builder.WriteByte(buf[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read the input: %w", err)
+		}
+	}
+	input := strings.TrimSpace(builder.String())
+	if input == "" {
+		return defaultValue, nil
+	}
+	return input, nil
+}
+
+// confirm asks a user to answer yes or no, and no is the default
+func confirm(ioIn io.Reader, ioErr io.Writer, message string) (bool, error) {
+	answer, err := promptInput(ioIn, ioErr, message+" (y/N)", "")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
 func writeFzfResult(ioOut io.Writer, out []byte, column int) error {
 	lineSeparator := "\n"
 	lines := strings.Split(strings.TrimSpace(string(out)), lineSeparator)
@@ -1,8 +1,13 @@
 package command
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,9 +16,20 @@ import (
 
 func TestMain(m *testing.M) {
 	backupRunCommandWithFzf := runCommandWithFzf
+	backupRunGitCommand := runGitCommand
+	backupRunGitCommandOutput := runGitCommandOutput
+	backupRunShellCommand := runShellCommand
+	backupExecutablePath := executablePath
 	defer func() {
+		runShellCommand = backupRunShellCommand
 		runCommandWithFzf = backupRunCommandWithFzf
+		runGitCommand = backupRunGitCommand
+		runGitCommandOutput = backupRunGitCommandOutput
+		executablePath = backupExecutablePath
 	}()
+	executablePath = func() (string, error) {
+		return "git-fzf", nil
+	}
 	os.Exit(m.Run())
 }
 
@@ -145,3 +161,157 @@ func TestBuildCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitFzfExpectResult(t *testing.T) {
+	testCases := []struct {
+		name      string
+		out       string
+		wantKey   string
+		wantLines []string
+	}{
+		{
+			name:      "enter",
+			out:       "\nabc Commit message1\nxyz Commit message2\n",
+			wantKey:   "",
+			wantLines: []string{"abc Commit message1", "xyz Commit message2"},
+		},
+		{
+			name:      "expected key",
+			out:       "alt-d\nabc Commit message1\n",
+			wantKey:   "alt-d",
+			wantLines: []string{"abc Commit message1"},
+		},
+		{
+			name:      "no selected line",
+			out:       "alt-p\n",
+			wantKey:   "alt-p",
+			wantLines: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotKey, gotLines := splitFzfExpectResult([]byte(tc.out))
+			assert.Equal(t, tc.wantKey, gotKey)
+			assert.Equal(t, tc.wantLines, gotLines)
+		})
+	}
+}
+
+func TestPromptInput(t *testing.T) {
+	testCases := []struct {
+		name         string
+		ioIn         string
+		message      string
+		defaultValue string
+		want         string
+		wantIOErr    string
+	}{
+		{
+			name:         "input",
+			ioIn:         " feature \nnext line\n",
+			message:      "Branch",
+			defaultValue: "",
+			want:         "feature",
+			wantIOErr:    "Branch: ",
+		},
+		{
+			name:         "default value",
+			ioIn:         "\n",
+			message:      "Branch",
+			defaultValue: "master",
+			want:         "master",
+			wantIOErr:    "Branch [master]: ",
+		},
+		{
+			name:         "EOF",
+			ioIn:         "",
+			message:      "Branch",
+			defaultValue: "master",
+			want:         "master",
+			wantIOErr:    "Branch [master]: ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotIOErr bytes.Buffer
+			got, gotErr := promptInput(strings.NewReader(tc.ioIn), &gotIOErr, tc.message, tc.defaultValue)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	testCases := []struct {
+		name string
+		ioIn string
+		want bool
+	}{
+		{
+			name: "yes",
+			ioIn: "y\n",
+			want: true,
+		},
+		{
+			name: "YES",
+			ioIn: "YES\n",
+			want: true,
+		},
+		{
+			name: "no",
+			ioIn: "n\n",
+			want: false,
+		},
+		{
+			name: "default",
+			ioIn: "\n",
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotIOErr bytes.Buffer
+			got, gotErr := confirm(strings.NewReader(tc.ioIn), &gotIOErr, "Remove?")
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, "Remove? (y/N): ", gotIOErr.String())
+		})
+	}
+}
+
+func TestGitTopLevel(t *testing.T) {
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		assert.Equal(t, "rev-parse --show-toplevel", strings.Join(args, " "))
+		return []byte("/repo\n"), nil
+	}
+	got, err := gitTopLevel(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "/repo", got)
+}
+
+func TestOpenEditor(t *testing.T) {
+	var gotCommandLine string
+	runShellCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, commandLine string) error {
+		gotCommandLine = commandLine
+		return nil
+	}
+	assert.NoError(t, openEditor(context.Background(), strings.NewReader(""), &bytes.Buffer{}, "it's.go", 3))
+	assert.Equal(t, `${EDITOR:-vi} +3 'it'\''s.go'`, gotCommandLine)
+}
+
+func TestShellQuoteInFzfOption(t *testing.T) {
+	for _, s := range []string{"main.go", "dir/main file.go", "it's", "--author=$USER `id`"} {
+		t.Run(s, func(t *testing.T) {
+			// The first shell parses the fzf option, and the second one runs the command in it like fzf
+			option, err := exec.Command("sh", "-c", fmt.Sprintf("printf %%s '%s'", "printf %s "+shellQuoteInFzfOption(s))).Output()
+			require.NoError(t, err)
+			got, err := exec.Command("sh", "-c", string(option)).Output()
+			require.NoError(t, err)
+			assert.Equal(t, s, string(got))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type grepCli struct {
+	revision  string
+	pathspecs []string
+	fzfQuery  string
+	fzfOption string
+}
+
+const (
+	// {{.line}} is highlighted and the preview window is scrolled to center it
+	grepFzfPreviewCommand      = "{{.show}} | (bat --color=always --style=numbers --highlight-line {{.line}} --file-name {{.path}} 2>/dev/null || cat -n)"
+	grepFzfPreviewWindowOption = "+{{.line}}-/2"
+	grepFzfEditorBindOption    = "alt-e:execute(${EDITOR:-vi} +{{.line}} {{.path}})"
+)
+
+func NewGrepSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "grep [<rev>] [-- <pathspec>...]",
+		Short: "git grep with fzf, searching again whenever the query changes",
+		Args:  cobra.MaximumNArgs(100),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			revision, pathspecs, err := parseGrepArgs(context.Background(), args, cmd.ArgsLenAtDash())
+			if err != nil {
+				return err
+			}
+
+			cli, err := newGrepCli(revision, pathspecs, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// parseGrepArgs splits arguments into a revision and pathspecs in the same way as git grep.
+// Without --, the first argument is a revision only if it's a valid revision.
+func parseGrepArgs(ctx context.Context, args []string, dash int) (string, []string, error) {
+	if dash >= 0 {
+		if dash > 1 {
+			return "", nil, fmt.Errorf("only one revision can be given before --: %s", strings.Join(args[:dash], " "))
+		}
+		revision := ""
+		if dash == 1 {
+			revision = args[0]
+		}
+		return revision, args[dash:], nil
+	}
+
+	if len(args) == 0 {
+		return "", args, nil
+	}
+	if _, err := runGitCommandOutput(ctx, "rev-parse", "--verify", "--quiet", args[0]+"^{tree}"); err != nil {
+		return "", args, nil
+	}
+	return args[0], args[1:], nil
+}
+
+func newGrepCli(revision string, pathspecs []string, fzfQuery string) (*grepCli, error) {
+	// git grep with a revision prints <rev>:<path>:<line>:<column>
+	pathField := "{1}"
+	lineField := "{2}"
+	show := "cat " + pathField
+	if revision != "" {
+		pathField = "{2}"
+		lineField = "{3}"
+		// Paths are relative to the current directory, and so is ./ of git show
+		show = "git show " + revision + ":./" + pathField
+	}
+	data := map[string]interface{}{
+		"show": show,
+		"path": pathField,
+		"line": lineField,
+	}
+	previewCommand, err := commandFromTemplate("preview", grepFzfPreviewCommand, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	previewWindowOption, err := commandFromTemplate("previewWindow", grepFzfPreviewWindowOption, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview window option: %w", err)
+	}
+	editorBindOption, err := commandFromTemplate("bind", grepFzfEditorBindOption, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf bind option: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --disabled --delimiter : --preview-window '%s' --bind 'change:reload(%s)' --bind '%s'",
+		fzfOption,
+		previewWindowOption,
+		grepListCommand(revision, pathspecs, "{q}", shellQuoteInFzfOption),
+		editorBindOption,
+	)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &grepCli{
+		revision:  revision,
+		pathspecs: pathspecs,
+		fzfQuery:  fzfQuery,
+		fzfOption: fzfOption,
+	}, nil
+}
+
+// grepListCommand returns git grep command to search with a query, which must be quoted for a shell.
+// The revision and pathspecs are quoted by the quote function.
+// Nothing is searched for an empty query because it matches every line.
+func grepListCommand(revision string, pathspecs []string, query string, quote func(string) string) string {
+	command := "git grep -n --column --color=always -e " + query
+	if revision != "" {
+		command = command + " " + quote(revision)
+	}
+	if len(pathspecs) > 0 {
+		command = command + " --"
+		for _, pathspec := range pathspecs {
+			command = command + " " + quote(pathspec)
+		}
+	}
+	return fmt.Sprintf("[ -n %s ] && %s || true", query, command)
+}
+
+func (c grepCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	command := fmt.Sprintf("%s | fzf %s", grepListCommand(c.revision, c.pathspecs, shellQuote(c.fzfQuery), shellQuote), c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	if err := c.writeFzfResult(ioOut, out); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeFzfResult writes <path>:<line>:<column> of each match, which many editors can open
+func (c grepCli) writeFzfResult(ioOut io.Writer, out []byte) error {
+	lineSeparator := "\n"
+	fieldSeparator := ":"
+	numFields := 3
+	if c.revision != "" {
+		numFields++
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), lineSeparator)
+	locations := make([]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.SplitN(line, fieldSeparator, numFields+1)
+		if len(fields) <= numFields {
+			continue
+		}
+		locations = append(locations, strings.Join(fields[numFields-3:numFields], fieldSeparator))
+	}
+	buf := bytes.NewBufferString(strings.Join(locations, lineSeparator) + lineSeparator)
+	if _, err := ioOut.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGrepSubcommand(t *testing.T) {
+	assert.NotNil(t, NewGrepSubcommand())
+}
+
+func TestNewGrepCommand(t *testing.T) {
+	testCases := []struct {
+		name      string
+		revision  string
+		pathspecs []string
+		fzfQuery  string
+		envVars   map[string]string
+		want      *grepCli
+		wantErr   error
+	}{
+		{
+			name:      "no options",
+			revision:  "",
+			pathspecs: []string{},
+			fzfQuery:  "",
+			want: &grepCli{
+				revision:  "",
+				pathspecs: []string{},
+				fzfQuery:  "",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --disabled --delimiter : --preview-window '%s' --bind '%s' --bind '%s'",
+					"cat {1} | (bat --color=always --style=numbers --highlight-line {2} --file-name {1} 2>/dev/null || cat -n)",
+					defaultFzfBindOption,
+					"+{2}-/2",
+					"change:reload([ -n {q} ] && git grep -n --column --color=always -e {q} || true)",
+					"alt-e:execute(${EDITOR:-vi} +{2} {1})",
+				),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			revision: "origin/master",
+			pathspecs: []string{
+				"*.go",
+				"README.md",
+			},
+			fzfQuery: "config",
+			want: &grepCli{
+				revision: "origin/master",
+				pathspecs: []string{
+					"*.go",
+					"README.md",
+				},
+				fzfQuery: "config",
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --disabled --delimiter : --preview-window '%s' --bind '%s' --bind '%s' --query config",
+					"git show origin/master:./{2} | (bat --color=always --style=numbers --highlight-line {3} --file-name {2} 2>/dev/null || cat -n)",
+					defaultFzfBindOption,
+					"+{3}-/2",
+					`change:reload([ -n {q} ] && git grep -n --column --color=always -e {q} '\''origin/master'\'' -- '\''*.go'\'' '\''README.md'\'' || true)`,
+					"alt-e:execute(${EDITOR:-vi} +{3} {2})",
+				),
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "GIT_FZF_FZF_OPTION includes invalid env",
+			revision:  "",
+			pathspecs: []string{},
+			fzfQuery:  "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newGrepCli(tc.revision, tc.pathspecs, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestGrepCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		sut               grepCli
+		wantErr           error
+		wantIO            string
+		wantIOErr         string
+	}{
+		{
+			name: "location output",
+			sut: grepCli{
+				pathspecs: []string{},
+				fzfQuery:  "it's",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				assert.Equal(t, fmt.Sprintf("%s | fzf %s",
+					`[ -n 'it'\''s' ] && git grep -n --column --color=always -e 'it'\''s' || true`,
+					fzfOption,
+				), commandLine)
+				return bytes.NewBufferString("README.md:1:3:it's fzf\ninternal/command/fzf.go:10:1:it's: colon\n").Bytes(), nil
+			},
+			wantErr:   nil,
+			wantIO:    "README.md:1:3\ninternal/command/fzf.go:10:1\n",
+			wantIOErr: "",
+		},
+		{
+			name: "location output with a revision",
+			sut: grepCli{
+				revision:  "origin/master",
+				pathspecs: []string{"*.md"},
+				fzfQuery:  "fzf",
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				assert.Equal(t, fmt.Sprintf("%s | fzf %s",
+					`[ -n 'fzf' ] && git grep -n --column --color=always -e 'fzf' 'origin/master' -- '*.md' || true`,
+					fzfOption,
+				), commandLine)
+				return bytes.NewBufferString("origin/master:README.md:1:3:git fzf\n").Bytes(), nil
+			},
+			wantErr:   nil,
+			wantIO:    "README.md:1:3\n",
+			wantIOErr: "",
+		},
+		{
+			name: "command with fzf error",
+			sut: grepCli{
+				pathspecs: []string{},
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, defaultWantErr
+			},
+			wantErr:   defaultWantErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: grepCli{
+				pathspecs: []string{},
+				fzfOption: fzfOption,
+			},
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, &exitErr
+			},
+			wantErr:   &exitErr,
+			wantIO:    "",
+			wantIOErr: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestParseGrepArgs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		dash          int
+		wantRevision  string
+		wantPathspecs []string
+		wantErr       bool
+	}{
+		{
+			name:          "no args",
+			args:          []string{},
+			dash:          -1,
+			wantRevision:  "",
+			wantPathspecs: []string{},
+		},
+		{
+			name:          "revision and pathspecs after --",
+			args:          []string{"origin/master", "*.go", "$HOME"},
+			dash:          1,
+			wantRevision:  "origin/master",
+			wantPathspecs: []string{"*.go", "$HOME"},
+		},
+		{
+			name:          "only pathspecs after --",
+			args:          []string{"*.go"},
+			dash:          0,
+			wantRevision:  "",
+			wantPathspecs: []string{"*.go"},
+		},
+		{
+			name:    "extra args before --",
+			args:    []string{"origin/master", "main.go", "*.go"},
+			dash:    2,
+			wantErr: true,
+		},
+		{
+			name:          "revision and pathspecs without --",
+			args:          []string{"origin/master", "*.go"},
+			dash:          -1,
+			wantRevision:  "origin/master",
+			wantPathspecs: []string{"*.go"},
+		},
+		{
+			name:          "only pathspecs without --",
+			args:          []string{"main.go", "*.md"},
+			dash:          -1,
+			wantRevision:  "",
+			wantPathspecs: []string{"main.go", "*.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "rev-parse --verify --quiet origin/master^{tree}":
+					return []byte("abc\n"), nil
+				case "rev-parse --verify --quiet main.go^{tree}":
+					return nil, errors.New("exit status 1")
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			gotRevision, gotPathspecs, gotErr := parseGrepArgs(context.Background(), tc.args, tc.dash)
+			assert.Equal(t, tc.wantErr, gotErr != nil)
+			if !tc.wantErr {
+				assert.Equal(t, tc.wantRevision, gotRevision)
+				assert.Equal(t, tc.wantPathspecs, gotPathspecs)
+			}
+		})
+	}
+}
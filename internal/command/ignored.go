@@ -0,0 +1,216 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type ignoredCli struct {
+	fzfOption string
+}
+
+// ignoreRule is a pattern of an ignore file which ignores a path
+type ignoreRule struct {
+	source  string
+	line    int
+	pattern string
+}
+
+const (
+	ignoredKeyAdd    = "alt-a"
+	ignoredKeyEditor = "alt-e"
+)
+
+func NewIgnoredSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "ignored",
+		Short: "See ignored files and the rules which ignore them with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			previewPath, err := flags.GetString("preview")
+			if err != nil {
+				return err
+			}
+			if previewPath != "" {
+				return previewIgnoreRule(context.Background(), os.Stdout, previewPath)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newIgnoredCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("preview", "", "Only print the rule which ignores the path relative to the top directory")
+	_ = flags.MarkHidden("preview")
+	return command
+}
+
+func newIgnoredCli(fzfQuery string) (*ignoredCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	previewCommand := shellQuoteInFzfOption(self) + " ignored --preview {}"
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --expect %s,%s", fzfOption, ignoredKeyAdd, ignoredKeyEditor)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &ignoredCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c ignoredCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// Paths of git status are relative to the top directory even in a subdirectory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The list is reloaded after each action
+	for {
+		paths, err := listIgnoredFiles(ctx)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			_, err := fmt.Fprintln(ioErr, "There is no ignored file")
+			return err
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, strings.NewReader(strings.Join(paths, "\n")+"\n"), ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, selected := splitFzfExpectResult(out)
+		if len(selected) == 0 {
+			return nil
+		}
+
+		switch key {
+		case ignoredKeyAdd:
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "add", "--force", "--"}, selected...)...); err != nil {
+				return fmt.Errorf("failed to add ignored files: %w", err)
+			}
+		case ignoredKeyEditor:
+			// Only the first file is used because an editor is opened once
+			rule, err := findIgnoreRule(ctx, top, selected[0])
+			if err != nil {
+				return err
+			}
+			source := rule.source
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(top, source)
+			}
+			if err := openEditor(ctx, ioIn, ioErr, source, rule.line); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(selected, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// listIgnoredFiles lists paths matched by ignore rules, relative to the top directory.
+// A directory is listed instead of its files if the directory itself is matched.
+func listIgnoredFiles(ctx context.Context) ([]string, error) {
+	out, err := runGitCommandOutput(ctx, "status", "--porcelain", "-z", "--ignored=matching", "--untracked-files=normal")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ignored files: %w", err)
+	}
+	var paths []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		if strings.HasPrefix(entry, "!! ") {
+			paths = append(paths, strings.TrimPrefix(entry, "!! "))
+		}
+	}
+	return paths, nil
+}
+
+// findIgnoreRule returns the rule which ignores a path relative to the top directory
+func findIgnoreRule(ctx context.Context, top string, path string) (*ignoreRule, error) {
+	out, err := runGitCommandOutput(ctx, "-C", top, "check-ignore", "--verbose", "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the rule which ignores %s: %w", path, err)
+	}
+	// The output is like <source>:<line>:<pattern><tab><path>
+	fields := strings.SplitN(strings.SplitN(strings.TrimRight(string(out), "\n"), "\t", 2)[0], ":", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected output of git check-ignore: %s", out)
+	}
+	line, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid line number of git check-ignore: %s", fields[1])
+	}
+	return &ignoreRule{
+		source:  fields[0],
+		line:    line,
+		pattern: fields[2],
+	}, nil
+}
+
+// previewIgnoreRule prints the rule which ignores a path and the ignore file with the line of the rule marked
+func previewIgnoreRule(ctx context.Context, ioOut io.Writer, path string) error {
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+	rule, err := findIgnoreRule(ctx, top, path)
+	if err != nil {
+		return err
+	}
+
+	var preview bytes.Buffer
+	fmt.Fprintf(&preview, "%s is ignored by %s at line %d of %s\n\n", path, rule.pattern, rule.line, rule.source)
+	source := rule.source
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(top, source)
+	}
+	// The ignore file may not be readable like a file in $HOME
+	if content, err := ioutil.ReadFile(source); err == nil {
+		for i, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+			marker := " "
+			if i+1 == rule.line {
+				marker = ">"
+			}
+			fmt.Fprintf(&preview, "%s %4d %s\n", marker, i+1, line)
+		}
+	}
+	if _, err := ioOut.Write(preview.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the preview: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIgnoredSubcommand(t *testing.T) {
+	assert.NotNil(t, NewIgnoredSubcommand())
+}
+
+func TestNewIgnoredCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *ignoredCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &ignoredCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --expect alt-a,alt-e", "'\\''git-fzf'\\'' ignored --preview {}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "log",
+			want: &ignoredCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --expect alt-a,alt-e --query log", "'\\''git-fzf'\\'' ignored --preview {}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newIgnoredCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestIgnoredCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		status            string
+		fzfOutputs        []string
+		fzfErr            error
+		wantErr           error
+		wantGitCommands   []string
+		wantShellCommands []string
+		wantIO            string
+		wantIOErr         string
+	}{
+		{
+			name:   "select files",
+			status: "?? new.go\x00!! build/\x00!! debug.log\x00",
+			fzfOutputs: []string{
+				"\nbuild/\ndebug.log\n",
+			},
+			wantErr: nil,
+			wantIO:  "build/\ndebug.log\n",
+		},
+		{
+			name:   "force add files",
+			status: "!! build/\x00!! debug.log\x00",
+			fzfOutputs: []string{
+				"alt-a\ndebug.log\n",
+				"\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C /repo add --force -- debug.log",
+			},
+		},
+		{
+			name:   "open the ignore file",
+			status: "!! build/\x00!! debug.log\x00",
+			fzfOutputs: []string{
+				"alt-e\ndebug.log\nbuild/\n",
+				"\n",
+			},
+			wantErr: nil,
+			wantShellCommands: []string{
+				"${EDITOR:-vi} +3 '/repo/.gitignore'",
+			},
+		},
+		{
+			name:      "no ignored file",
+			status:    "?? new.go\x00",
+			wantErr:   nil,
+			wantIOErr: "There is no ignored file\n",
+		},
+		{
+			name:    "command with fzf error",
+			status:  "!! build/\x00",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			status:  "!! build/\x00",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := ignoredCli{
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				case "status --porcelain -z --ignored=matching --untracked-files=normal":
+					return []byte(tc.status), nil
+				case "-C /repo check-ignore --verbose -- debug.log":
+					return []byte(".gitignore:3:*.log\tdebug.log\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				list, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(tc.status, "?? new.go\x00", ""), "!! ", ""), "\x00", "\n"), string(list))
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+			var gotShellCommands []string
+			runShellCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, commandLine string) error {
+				gotShellCommands = append(gotShellCommands, commandLine)
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(""), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantShellCommands, gotShellCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestFindIgnoreRule(t *testing.T) {
+	testCases := []struct {
+		name    string
+		out     string
+		want    *ignoreRule
+		wantErr error
+	}{
+		{
+			name: "pattern in .gitignore",
+			out:  "dir/.gitignore:12:*.log\tdir/debug.log\n",
+			want: &ignoreRule{
+				source:  "dir/.gitignore",
+				line:    12,
+				pattern: "*.log",
+			},
+		},
+		{
+			name: "pattern with a colon",
+			out:  ".git/info/exclude:1:a:b\ta:b\n",
+			want: &ignoreRule{
+				source:  ".git/info/exclude",
+				line:    1,
+				pattern: "a:b",
+			},
+		},
+		{
+			name:    "unexpected output",
+			out:     "\n",
+			wantErr: fmt.Errorf("unexpected output of git check-ignore: %s", "\n"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				return []byte(tc.out), nil
+			}
+			got, gotErr := findIgnoreRule(context.Background(), "/repo", "debug.log")
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestPreviewIgnoreRule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("build/\n*.log\n"), 0644))
+
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "rev-parse --show-toplevel":
+			return []byte(dir + "\n"), nil
+		case fmt.Sprintf("-C %s check-ignore --verbose -- debug.log", dir):
+			return []byte(".gitignore:2:*.log\tdebug.log\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	}
+	var gotIOOut bytes.Buffer
+	assert.NoError(t, previewIgnoreRule(context.Background(), &gotIOOut, "debug.log"))
+	assert.Equal(t, "debug.log is ignored by *.log at line 2 of .gitignore\n\n     1 build/\n>    2 *.log\n", gotIOOut.String())
+}
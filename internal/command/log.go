@@ -1,6 +1,7 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -21,7 +22,7 @@ const (
 )
 
 func NewLogSubcommand() *cobra.Command {
-	return &cobra.Command{
+	command := &cobra.Command{
 		Use:   "log [<commit>[..<commit>]] [-- <git options>]",
 		Short: "git log with fzf",
 		Args:  cobra.MaximumNArgs(100),
@@ -31,6 +32,54 @@ func NewLogSubcommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			searchList, err := flags.GetBool("search-list")
+			if err != nil {
+				return err
+			}
+			searchPreview, err := flags.GetString("search-preview")
+			if err != nil {
+				return err
+			}
+			if searchList || searchPreview != "" {
+				modeFile, err := flags.GetString("search-mode-file")
+				if err != nil {
+					return err
+				}
+				query, err := flags.GetString("search-query")
+				if err != nil {
+					return err
+				}
+				if searchList {
+					return listLogSearch(context.Background(), os.Stdout, os.Stderr, modeFile, query, args)
+				}
+				return previewLogSearch(context.Background(), os.Stdout, modeFile, query, searchPreview)
+			}
+
+			search, err := flags.GetBool("search")
+			if err != nil {
+				return err
+			}
+			files, err := flags.GetBool("files")
+			if err != nil {
+				return err
+			}
+			if search && files {
+				return fmt.Errorf("--search and --files can't be used together")
+			}
+			if search {
+				cli, err := newLogSearchCli(args, fzfQuery)
+				if err != nil {
+					return err
+				}
+				return cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr)
+			}
+			if files {
+				cli, err := newLogFilesCli(args, fzfQuery)
+				if err != nil {
+					return err
+				}
+				return cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr)
+			}
 
 			cli, err := newLogCli(args, fzfQuery)
 			if err != nil {
@@ -42,6 +91,18 @@ func NewLogSubcommand() *cobra.Command {
 			return nil
 		},
 	}
+	flags := command.Flags()
+	flags.Bool("files", false, "Select files changed in a commit after the commit is selected, and print <commit>:<path>")
+	flags.Bool("search", false, "Search commits by git log whenever the query changes, by messages, strings or regular expressions in diffs")
+	flags.Bool("search-list", false, "Only print commits matched with the query in the mode of the mode file")
+	flags.String("search-preview", "", "Only print the commit with lines matched with the query highlighted")
+	flags.String("search-mode-file", "", "The file which has the current mode of the search")
+	flags.String("search-query", "", "The query of the search")
+	_ = flags.MarkHidden("search-list")
+	_ = flags.MarkHidden("search-preview")
+	_ = flags.MarkHidden("search-mode-file")
+	_ = flags.MarkHidden("search-query")
+	return command
 }
 
 func newLogCli(gitOptions []string, fzfQuery string) (*logCli, error) {
@@ -90,3 +151,20 @@ func (c logCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr
 	}
 	return nil
 }
+
+// pickCommit returns a commit picked by git fzf log, or an empty string if it's canceled
+func pickCommit(ctx context.Context, ioIn io.Reader, ioErr io.Writer, gitOptions []string, prompt string) (string, error) {
+	// The options are only for the list, because the preview shows a revision in them with a selected commit
+	cli, err := newLogCli([]string{}, "")
+	if err != nil {
+		return "", err
+	}
+	cli.listOptions = gitOptions
+	cli.fzfOption = fmt.Sprintf("%s --no-multi --prompt '%s> '", cli.fzfOption, prompt)
+
+	var out bytes.Buffer
+	if err := cli.Run(ctx, ioIn, &out, ioErr); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,146 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// logFilesCli selects a commit by git log, and then files changed in the commit
+type logFilesCli struct {
+	listOptions   []string
+	fzfQuery      string
+	fzfOption     string
+	fileFzfOption string
+}
+
+const (
+	// The diff of a merge commit is shown against its first parent. Paths of git diff-tree are relative to the top directory.
+	logFileFzfPreviewCommand = `cd "$(git rev-parse --show-toplevel)" && git show --color --format= -m --first-parent {{.commit}} -- {{.path}}`
+
+	logFilesKeyBack = "alt-b"
+)
+
+func newLogFilesCli(gitOptions []string, fzfQuery string) (*logFilesCli, error) {
+	cli, err := newLogCli(gitOptions, "")
+	if err != nil {
+		return nil, err
+	}
+	// The query is printed to start fzf again with the same query when a user goes back from the list of files
+	fzfOption := cli.fzfOption + " --no-multi --print-query"
+
+	filePreviewCommand, err := commandFromTemplate("preview", logFileFzfPreviewCommand, map[string]interface{}{
+		"commit": "{1}",
+		"path":   "{3}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	fileFzfOption, err := getFzfOption(filePreviewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fileFzfOption = fmt.Sprintf("%s --delimiter '\\t' --with-nth 2.. --expect %s", fileFzfOption, logFilesKeyBack)
+
+	return &logFilesCli{
+		listOptions:   gitOptions,
+		fzfQuery:      fzfQuery,
+		fzfOption:     fzfOption,
+		fileFzfOption: fileFzfOption,
+	}, nil
+}
+
+func (c logFilesCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	listCommand := fmt.Sprintf("git log --color --oneline %s", strings.Join(c.listOptions, " "))
+	query := c.fzfQuery
+	position := 0
+	for {
+		fzfOption := c.fzfOption
+		if query != "" {
+			fzfOption = fzfOption + " --query " + shellQuote(query)
+		}
+		if position > 0 {
+			fzfOption = fmt.Sprintf("%s --bind 'load:pos(%d)'", fzfOption, position)
+		}
+		command := fmt.Sprintf("%s | fzf %s", listCommand, fzfOption)
+		out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		query = lines[0]
+		if len(lines) < 2 {
+			return nil
+		}
+		commit := strings.Fields(lines[1])[0]
+
+		list, err := listLogFiles(ctx, commit)
+		if err != nil {
+			return err
+		}
+		command = fmt.Sprintf("fzf %s", c.fileFzfOption)
+		out, err = runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, selected := splitFzfExpectResult(out)
+		if key == logFilesKeyBack {
+			position = logCommitPosition(ctx, listCommand, query, commit)
+			continue
+		}
+
+		var result strings.Builder
+		for _, line := range selected {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			result.WriteString(fields[0] + ":" + fields[2] + "\n")
+		}
+		if _, err := io.WriteString(ioOut, result.String()); err != nil {
+			return fmt.Errorf("failed to output the result: %w", err)
+		}
+		return nil
+	}
+}
+
+// listLogFiles lists files changed in a commit against its first parent like <commit><tab><status><tab><path>
+func listLogFiles(ctx context.Context, commit string) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, "diff-tree", "-r", "--no-commit-id", "--name-status", "--root", "--diff-merges=first-parent", commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", commit, err)
+	}
+	var list bytes.Buffer
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			fmt.Fprintf(&list, "%s\t%s\n", commit, line)
+		}
+	}
+	return list.Bytes(), nil
+}
+
+// logCommitPosition returns the position of a commit in the list filtered by a query in the same way as fzf,
+// which is used to move the cursor to the commit again. 0 is returned if the commit isn't found.
+func logCommitPosition(ctx context.Context, listCommand string, query string, commit string) int {
+	command := fmt.Sprintf("%s | fzf --ansi --filter %s", listCommand, shellQuote(query))
+	out, err := runCommandWithFzf(ctx, command, nil, ioutil.Discard)
+	if err != nil {
+		// fzf --filter exits with 1 if nothing matches
+		return 0
+	}
+	for i, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == commit {
+			return i + 1
+		}
+	}
+	return 0
+}
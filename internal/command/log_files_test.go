@@ -0,0 +1,290 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogFilesCommand(t *testing.T) {
+	fileFzfOption := fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-b", `cd "$(git rev-parse --show-toplevel)" && git show --color --format= -m --first-parent {1} -- {3}`, defaultFzfBindOption)
+
+	testCases := []struct {
+		name       string
+		gitOptions []string
+		fzfQuery   string
+		envVars    map[string]string
+		want       *logFilesCli
+		wantErr    error
+	}{
+		{
+			name:       "no options",
+			gitOptions: []string{},
+			fzfQuery:   "",
+			want: &logFilesCli{
+				listOptions:   []string{},
+				fzfQuery:      "",
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --print-query", "git show --color  {1}", defaultFzfBindOption),
+				fileFzfOption: fileFzfOption,
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "all options",
+			gitOptions: []string{"origin/master"},
+			fzfQuery:   "fix bug",
+			want: &logFilesCli{
+				listOptions:   []string{"origin/master"},
+				fzfQuery:      "fix bug",
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --print-query", "git show --color origin/master {1}", defaultFzfBindOption),
+				fileFzfOption: fileFzfOption,
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "GIT_FZF_FZF_OPTION includes invalid env",
+			gitOptions: []string{},
+			fzfQuery:   "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newLogFilesCli(tc.gitOptions, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestLogFilesCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	fileFzfOption := "--layout reverse"
+	listCommand := "git log --color --oneline origin/master"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name string
+		// fzfOutputs are outputs of each fzf command
+		fzfOutputs      map[string][]string
+		fzfErr          error
+		wantErr         error
+		wantFzfCommands []string
+		wantIO          string
+	}{
+		{
+			name: "select files",
+			fzfOutputs: map[string][]string{
+				"list": {"fix\nabc Fix bug\n"},
+				"files": {
+					"\nabc\tM\tmain.go\nabc\tA\tdir/new file.go\n",
+				},
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				fmt.Sprintf("%s | fzf %s --query 'it'\\''s'", listCommand, fzfOption),
+				"fzf " + fileFzfOption,
+			},
+			wantIO: "abc:main.go\nabc:dir/new file.go\n",
+		},
+		{
+			name: "go back to the list of commits",
+			fzfOutputs: map[string][]string{
+				"list": {
+					"fix bug\nxyz Fix bug 2\n",
+					"\nabc Fix bug\n",
+				},
+				"files": {
+					"alt-b\nxyz\tM\tmain.go\n",
+					"\nabc\tD\told.go\n",
+				},
+				"filter": {"abc Fix bug\nxyz Fix bug 2\n"},
+			},
+			wantErr: nil,
+			wantFzfCommands: []string{
+				fmt.Sprintf("%s | fzf %s --query 'it'\\''s'", listCommand, fzfOption),
+				"fzf " + fileFzfOption,
+				fmt.Sprintf("%s | fzf --ansi --filter 'fix bug'", listCommand),
+				fmt.Sprintf("%s | fzf %s --query 'fix bug' --bind 'load:pos(2)'", listCommand, fzfOption),
+				"fzf " + fileFzfOption,
+			},
+			wantIO: "abc:old.go\n",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+			wantFzfCommands: []string{
+				fmt.Sprintf("%s | fzf %s --query 'it'\\''s'", listCommand, fzfOption),
+			},
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+			wantFzfCommands: []string{
+				fmt.Sprintf("%s | fzf %s --query 'it'\\''s'", listCommand, fzfOption),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := logFilesCli{
+				listOptions:   []string{"origin/master"},
+				fzfQuery:      "it's",
+				fzfOption:     fzfOption,
+				fileFzfOption: fileFzfOption,
+			}
+
+			var gotFzfCommands []string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				gotFzfCommands = append(gotFzfCommands, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				kind := "list"
+				if strings.Contains(commandLine, "--filter") {
+					kind = "filter"
+				} else if strings.HasPrefix(commandLine, "fzf ") {
+					kind = "files"
+					list, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.True(t, strings.HasPrefix(string(list), "abc\tM\tmain.go\n") || strings.HasPrefix(string(list), "xyz\tM\tmain.go\n"), string(list))
+				}
+				require.True(t, len(tc.fzfOutputs[kind]) > 0, kind)
+				out := tc.fzfOutputs[kind][0]
+				tc.fzfOutputs[kind] = tc.fzfOutputs[kind][1:]
+				return []byte(out), nil
+			}
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, "diff-tree -r --no-commit-id --name-status --root --diff-merges=first-parent", strings.Join(args[:len(args)-1], " "))
+				return []byte("M\tmain.go\n"), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantFzfCommands, gotFzfCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
+
+func TestLogCommitPosition(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fzfOutput string
+		fzfErr    error
+		want      int
+	}{
+		{
+			name:      "found",
+			fzfOutput: "abc Fix bug\nxyz Fix bug 2\n",
+			want:      2,
+		},
+		{
+			name:      "not found",
+			fzfOutput: "abc Fix bug\n",
+			want:      0,
+		},
+		{
+			name:   "nothing matches",
+			fzfErr: errors.New("exit status 1"),
+			want:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "git log --color --oneline | fzf --ansi --filter 'bug'", commandLine)
+				return []byte(tc.fzfOutput), tc.fzfErr
+			}
+			assert.Equal(t, tc.want, logCommitPosition(context.Background(), "git log --color --oneline", "bug", "xyz"))
+		})
+	}
+}
+
+func TestListLogFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(path string, message string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(path+"\n"), 0600))
+		git("add", path)
+		git("commit", "-q", "-m", message)
+	}
+	git("init", "-q")
+	commit("root.txt", "root")
+	git("checkout", "-q", "-b", "feature")
+	commit("feature.txt", "feature")
+	git("checkout", "-q", "-")
+	commit("main.txt", "main")
+	git("merge", "-q", "--no-edit", "feature")
+
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	}
+
+	testCases := []struct {
+		name     string
+		revision string
+		want     string
+	}{
+		{
+			name:     "merge commit against its first parent",
+			revision: "HEAD",
+			want:     "\tA\tfeature.txt\n",
+		},
+		{
+			name:     "root commit",
+			revision: "HEAD^1^1",
+			want:     "\tA\troot.txt\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			commit := git("rev-parse", tc.revision)
+			got, err := listLogFiles(context.Background(), commit)
+			require.NoError(t, err)
+			assert.Equal(t, commit+tc.want, string(got))
+		})
+	}
+}
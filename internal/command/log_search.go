@@ -0,0 +1,227 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// logSearchCli searches commits by git log whenever the query changes
+type logSearchCli struct {
+	listOptions []string
+	fzfQuery    string
+	self        string
+}
+
+const (
+	// Commits are searched by their messages with --grep
+	logSearchModeGrep = "grep"
+	// Commits are searched by the number of occurrences of a string in their diffs with -S
+	logSearchModePickaxe = "pickaxe"
+	// Commits are searched by a regular expression in added or removed lines with -G
+	logSearchModeRegex = "regex"
+
+	logSearchKeyGrep    = "alt-g"
+	logSearchKeyPickaxe = "alt-s"
+	logSearchKeyRegex   = "alt-r"
+
+	ansiReverse      = "\x1b[7m"
+	ansiReverseReset = "\x1b[27m"
+)
+
+func newLogSearchCli(gitOptions []string, fzfQuery string) (*logSearchCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	return &logSearchCli{
+		listOptions: gitOptions,
+		fzfQuery:    fzfQuery,
+		self:        self,
+	}, nil
+}
+
+// listCommand returns the command to list commits matched with a query, which must be quoted for a shell.
+// The command, the path of the mode file and the list options are quoted by the quote function.
+func (c logSearchCli) listCommand(modeFile string, query string, quote func(string) string) string {
+	command := fmt.Sprintf("%s log --search-list --search-mode-file %s --search-query %s", quote(c.self), quote(modeFile), query)
+	if len(c.listOptions) > 0 {
+		command = command + " --"
+		for _, option := range c.listOptions {
+			command = command + " " + quote(option)
+		}
+	}
+	return command
+}
+
+// fzfOption returns the fzf option. The mode file has the current mode, which is changed by keys.
+func (c logSearchCli) fzfOption(modeFile string) (string, error) {
+	previewCommand := fmt.Sprintf("%s log --search-preview {1} --search-mode-file %s --search-query {q}", shellQuoteInFzfOption(c.self), shellQuoteInFzfOption(modeFile))
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return "", fmt.Errorf("failed to get fzf option: %w", err)
+	}
+
+	listCommand := c.listCommand(modeFile, "{q}", shellQuoteInFzfOption)
+	fzfOption = fmt.Sprintf("%s --disabled --prompt '%s> ' --bind 'change:reload(%s)'", fzfOption, logSearchModeGrep, listCommand)
+	for _, mode := range []struct {
+		key  string
+		name string
+	}{
+		{logSearchKeyGrep, logSearchModeGrep},
+		{logSearchKeyPickaxe, logSearchModePickaxe},
+		{logSearchKeyRegex, logSearchModeRegex},
+	} {
+		fzfOption = fmt.Sprintf("%s --bind '%s:execute-silent(echo %s > %s)+change-prompt(%s> )+reload(%s)'",
+			fzfOption, mode.key, mode.name, shellQuoteInFzfOption(modeFile), mode.name, listCommand)
+	}
+	if c.fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + c.fzfQuery
+	}
+	return fzfOption, nil
+}
+
+func (c logSearchCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	modeFile, err := ioutil.TempFile("", "git-fzf-log-search")
+	if err != nil {
+		return fmt.Errorf("failed to create the mode file: %w", err)
+	}
+	defer os.Remove(modeFile.Name())
+	if _, err := modeFile.WriteString(logSearchModeGrep + "\n"); err != nil {
+		modeFile.Close()
+		return fmt.Errorf("failed to write the mode file: %w", err)
+	}
+	if err := modeFile.Close(); err != nil {
+		return fmt.Errorf("failed to write the mode file: %w", err)
+	}
+
+	fzfOption, err := c.fzfOption(modeFile.Name())
+	if err != nil {
+		return err
+	}
+	command := fmt.Sprintf("%s | fzf %s", c.listCommand(modeFile.Name(), shellQuote(c.fzfQuery), shellQuote), fzfOption)
+	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+	if err := writeFzfResult(ioOut, out, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readLogSearchMode returns the mode in the mode file, or the grep mode if it's unknown
+func readLogSearchMode(modeFile string) (string, error) {
+	content, err := ioutil.ReadFile(modeFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the mode file: %w", err)
+	}
+	mode := strings.TrimSpace(string(content))
+	if mode != logSearchModePickaxe && mode != logSearchModeRegex {
+		return logSearchModeGrep, nil
+	}
+	return mode, nil
+}
+
+// logSearchOptions returns options of git log and git show to search with a query in a mode
+func logSearchOptions(mode string, query string) []string {
+	if query == "" {
+		return nil
+	}
+	switch mode {
+	case logSearchModePickaxe:
+		return []string{"-S", query}
+	case logSearchModeRegex:
+		return []string{"-G", query}
+	}
+	return []string{"--grep", query}
+}
+
+// listLogSearch prints commits matched with a query in the current mode. All commits are printed for an empty query.
+func listLogSearch(ctx context.Context, ioOut io.Writer, ioErr io.Writer, modeFile string, query string, logOptions []string) error {
+	mode, err := readLogSearchMode(modeFile)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"log", "--color", "--oneline"}, logSearchOptions(mode, query)...)
+	args = append(args, logOptions...)
+	if err := runGitCommand(ctx, nil, ioOut, ioErr, args...); err != nil {
+		return fmt.Errorf("failed to search commits: %w", err)
+	}
+	return nil
+}
+
+// previewLogSearch prints a commit with lines matched with a query highlighted.
+// Only files matched with the query are shown for the pickaxe and regex modes.
+func previewLogSearch(ctx context.Context, ioOut io.Writer, modeFile string, query string, commit string) error {
+	mode, err := readLogSearchMode(modeFile)
+	if err != nil {
+		return err
+	}
+	args := []string{"show", "--color=never"}
+	if mode != logSearchModeGrep {
+		args = append(args, logSearchOptions(mode, query)...)
+	}
+	out, err := runGitCommandOutput(ctx, append(args, commit)...)
+	if err != nil {
+		return fmt.Errorf("failed to show %s: %w", commit, err)
+	}
+
+	var pattern *regexp.Regexp
+	if query != "" {
+		// A string of the pickaxe mode or an invalid regular expression is matched literally
+		if mode != logSearchModePickaxe {
+			pattern, _ = regexp.Compile(query)
+		}
+		if pattern == nil {
+			pattern = regexp.MustCompile(regexp.QuoteMeta(query))
+		}
+	}
+
+	var preview bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		preview.WriteString(colorDiffLine(line, pattern) + "\n")
+	}
+	if _, err := ioOut.Write(preview.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the preview: %w", err)
+	}
+	return nil
+}
+
+// colorDiffLine colors a line of git show in the same way as git, and highlights matches of a pattern.
+// A pattern is matched with a changed line without + or -, so that ^ matches the beginning of the line.
+func colorDiffLine(line string, pattern *regexp.Regexp) string {
+	highlight := func(s string) string {
+		if pattern == nil {
+			return s
+		}
+		return pattern.ReplaceAllStringFunc(s, func(match string) string {
+			return ansiReverse + match + ansiReverseReset
+		})
+	}
+
+	switch {
+	case strings.HasPrefix(line, "commit "):
+		return "\x1b[33m" + line + "\x1b[m"
+	case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+		return "\x1b[1m" + line + "\x1b[m"
+	case strings.HasPrefix(line, "@@"):
+		return "\x1b[36m" + line + "\x1b[m"
+	case strings.HasPrefix(line, "+"):
+		return "\x1b[32m+" + highlight(line[1:]) + "\x1b[m"
+	case strings.HasPrefix(line, "-"):
+		return "\x1b[31m-" + highlight(line[1:]) + "\x1b[m"
+	}
+	return highlight(line)
+}
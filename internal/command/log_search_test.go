@@ -0,0 +1,358 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogSearchCli(t *testing.T) {
+	got, err := newLogSearchCli([]string{"origin/master"}, "fix")
+	require.NoError(t, err)
+	assert.Equal(t, &logSearchCli{
+		listOptions: []string{"origin/master"},
+		fzfQuery:    "fix",
+		self:        "git-fzf",
+	}, got)
+}
+
+func TestLogSearchCli_listCommand(t *testing.T) {
+	testCases := []struct {
+		name        string
+		listOptions []string
+		quote       func(string) string
+		want        string
+	}{
+		{
+			name:        "no options",
+			listOptions: []string{},
+			quote:       shellQuote,
+			want:        `'git-fzf' log --search-list --search-mode-file '/tmp/mode' --search-query {q}`,
+		},
+		{
+			name:        "options",
+			listOptions: []string{"origin/master", "--", "it's \"main\".go"},
+			quote:       shellQuote,
+			want:        `'git-fzf' log --search-list --search-mode-file '/tmp/mode' --search-query {q} -- 'origin/master' '--' 'it'\''s "main".go'`,
+		},
+		{
+			name:        "options in an fzf option",
+			listOptions: []string{"origin/master", "--", "main go"},
+			quote:       shellQuoteInFzfOption,
+			want:        `'\''git-fzf'\'' log --search-list --search-mode-file '\''/tmp/mode'\'' --search-query {q} -- '\''origin/master'\'' '\''--'\'' '\''main go'\''`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := logSearchCli{listOptions: tc.listOptions, self: "git-fzf"}
+			assert.Equal(t, tc.want, sut.listCommand("/tmp/mode", "{q}", tc.quote))
+		})
+	}
+}
+
+func TestLogSearchCli_fzfOption(t *testing.T) {
+	listCommand := `'\''git-fzf'\'' log --search-list --search-mode-file '\''/tmp/mode'\'' --search-query {q}`
+	bindOption := fmt.Sprintf(`--disabled --prompt 'grep> ' --bind 'change:reload(%[1]s)'`+
+		` --bind 'alt-g:execute-silent(echo grep > '\''/tmp/mode'\'')+change-prompt(grep> )+reload(%[1]s)'`+
+		` --bind 'alt-s:execute-silent(echo pickaxe > '\''/tmp/mode'\'')+change-prompt(pickaxe> )+reload(%[1]s)'`+
+		` --bind 'alt-r:execute-silent(echo regex > '\''/tmp/mode'\'')+change-prompt(regex> )+reload(%[1]s)'`, listCommand)
+	previewCommand := `'\''git-fzf'\'' log --search-preview {1} --search-mode-file '\''/tmp/mode'\'' --search-query {q}`
+
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "no query",
+			fzfQuery: "",
+			want:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s %s", previewCommand, defaultFzfBindOption, bindOption),
+			wantErr:  nil,
+		},
+		{
+			name:     "query",
+			fzfQuery: "fix",
+			want:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s %s --query fix", previewCommand, defaultFzfBindOption, bindOption),
+			wantErr:  nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    "",
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			sut := logSearchCli{listOptions: []string{}, fzfQuery: tc.fzfQuery, self: "git-fzf"}
+			got, gotErr := sut.fzfOption("/tmp/mode")
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestLogSearchCli_Run(t *testing.T) {
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name      string
+		fzfOutput string
+		fzfErr    error
+		wantErr   error
+		wantIO    string
+	}{
+		{
+			name:      "select commits",
+			fzfOutput: "abc Fix bug\nxyz Add feature\n",
+			wantErr:   nil,
+			wantIO:    "abc\nxyz\n",
+		},
+		{
+			name:      "nothing selected",
+			fzfOutput: "",
+			wantErr:   nil,
+			wantIO:    "",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+			wantIO:  "",
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+			wantIO:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := logSearchCli{
+				listOptions: []string{"origin/master"},
+				fzfQuery:    "it's",
+				self:        "git-fzf",
+			}
+
+			var modeFile string
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				fields := strings.SplitN(commandLine, `--search-mode-file '`, 2)
+				require.Len(t, fields, 2)
+				modeFile = strings.SplitN(fields[1], `'`, 2)[0]
+				assert.True(t, strings.HasPrefix(commandLine, fmt.Sprintf(
+					`'git-fzf' log --search-list --search-mode-file '%s' --search-query 'it'\''s' -- 'origin/master' | fzf `, modeFile)), commandLine)
+				assert.True(t, strings.HasSuffix(commandLine, " --query it's"), commandLine)
+
+				content, err := ioutil.ReadFile(modeFile)
+				require.NoError(t, err)
+				assert.Equal(t, "grep\n", string(content))
+				return []byte(tc.fzfOutput), tc.fzfErr
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+
+			// The mode file is removed after fzf exits
+			_, err := os.Stat(modeFile)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func writeLogSearchModeFile(t *testing.T, dir string, mode string) string {
+	path := filepath.Join(dir, "mode")
+	require.NoError(t, ioutil.WriteFile(path, []byte(mode+"\n"), 0600))
+	return path
+}
+
+func TestReadLogSearchMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "grep", content: "grep", want: logSearchModeGrep},
+		{name: "pickaxe", content: "pickaxe", want: logSearchModePickaxe},
+		{name: "regex", content: "regex", want: logSearchModeRegex},
+		{name: "unknown", content: "unknown", want: logSearchModeGrep},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readLogSearchMode(writeLogSearchModeFile(t, dir, tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err = readLogSearchMode(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestLogSearchOptions(t *testing.T) {
+	assert.Nil(t, logSearchOptions(logSearchModeGrep, ""))
+	assert.Equal(t, []string{"--grep", "fix"}, logSearchOptions(logSearchModeGrep, "fix"))
+	assert.Equal(t, []string{"-S", "fix"}, logSearchOptions(logSearchModePickaxe, "fix"))
+	assert.Equal(t, []string{"-G", "fix"}, logSearchOptions(logSearchModeRegex, "fix"))
+}
+
+func TestListLogSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		mode     string
+		query    string
+		gitErr   error
+		wantArgs string
+		wantErr  bool
+	}{
+		{
+			name:     "all commits",
+			mode:     logSearchModeGrep,
+			query:    "",
+			wantArgs: "log --color --oneline origin/master",
+		},
+		{
+			name:     "grep",
+			mode:     logSearchModeGrep,
+			query:    "fix bug",
+			wantArgs: "log --color --oneline --grep fix bug origin/master",
+		},
+		{
+			name:     "pickaxe",
+			mode:     logSearchModePickaxe,
+			query:    "main",
+			wantArgs: "log --color --oneline -S main origin/master",
+		},
+		{
+			name:     "git error",
+			mode:     logSearchModeRegex,
+			query:    "[",
+			gitErr:   errors.New("invalid regex"),
+			wantArgs: "log --color --oneline -G [ origin/master",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				assert.Equal(t, tc.wantArgs, strings.Join(args, " "))
+				_, err := io.WriteString(ioOut, "abc Fix bug\n")
+				require.NoError(t, err)
+				return tc.gitErr
+			}
+
+			var gotIOOut bytes.Buffer
+			gotErr := listLogSearch(context.Background(), &gotIOOut, ioutil.Discard, writeLogSearchModeFile(t, dir, tc.mode), tc.query, []string{"origin/master"})
+			assert.Equal(t, tc.wantErr, gotErr != nil)
+			assert.Equal(t, "abc Fix bug\n", gotIOOut.String())
+		})
+	}
+}
+
+func TestPreviewLogSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	show := "commit abc\nAuthor: a <a@example.com>\n\n    Fix a bug\n\ndiff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a.b\n+a+b\n"
+
+	testCases := []struct {
+		name     string
+		mode     string
+		query    string
+		wantArgs string
+		want     string
+	}{
+		{
+			name:     "no query",
+			mode:     logSearchModeGrep,
+			query:    "",
+			wantArgs: "show --color=never abc",
+			want:     "\x1b[33mcommit abc\x1b[m\nAuthor: a <a@example.com>\n\n    Fix a bug\n\n\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[1m--- a/main.go\x1b[m\n\x1b[1m+++ b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m\n\x1b[31m-a.b\x1b[m\n\x1b[32m+a+b\x1b[m\n",
+		},
+		{
+			name:     "grep",
+			mode:     logSearchModeGrep,
+			query:    "b[a-z]g",
+			wantArgs: "show --color=never abc",
+			want:     "\x1b[33mcommit abc\x1b[m\nAuthor: a <a@example.com>\n\n    Fix a \x1b[7mbug\x1b[27m\n\n\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[1m--- a/main.go\x1b[m\n\x1b[1m+++ b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m\n\x1b[31m-a.b\x1b[m\n\x1b[32m+a+b\x1b[m\n",
+		},
+		{
+			name:     "pickaxe",
+			mode:     logSearchModePickaxe,
+			query:    "a+b",
+			wantArgs: "show --color=never -S a+b abc",
+			want:     "\x1b[33mcommit abc\x1b[m\nAuthor: a <a@example.com>\n\n    Fix a bug\n\n\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[1m--- a/main.go\x1b[m\n\x1b[1m+++ b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m\n\x1b[31m-a.b\x1b[m\n\x1b[32m+\x1b[7ma+b\x1b[27m\x1b[m\n",
+		},
+		{
+			name:     "regex",
+			mode:     logSearchModeRegex,
+			query:    "^a.b",
+			wantArgs: "show --color=never -G ^a.b abc",
+			want:     "\x1b[33mcommit abc\x1b[m\nAuthor: a <a@example.com>\n\n    Fix a bug\n\n\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[1m--- a/main.go\x1b[m\n\x1b[1m+++ b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m\n\x1b[31m-\x1b[7ma.b\x1b[27m\x1b[m\n\x1b[32m+\x1b[7ma+b\x1b[27m\x1b[m\n",
+		},
+		{
+			name:     "invalid regex",
+			mode:     logSearchModeRegex,
+			query:    "a.b(",
+			wantArgs: "show --color=never -G a.b( abc",
+			want:     "\x1b[33mcommit abc\x1b[m\nAuthor: a <a@example.com>\n\n    Fix a bug\n\n\x1b[1mdiff --git a/main.go b/main.go\x1b[m\n\x1b[1m--- a/main.go\x1b[m\n\x1b[1m+++ b/main.go\x1b[m\n\x1b[36m@@ -1 +1 @@\x1b[m\n\x1b[31m-a.b\x1b[m\n\x1b[32m+a+b\x1b[m\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				assert.Equal(t, tc.wantArgs, strings.Join(args, " "))
+				return []byte(show), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			require.NoError(t, previewLogSearch(context.Background(), &gotIOOut, writeLogSearchModeFile(t, dir, tc.mode), tc.query, "abc"))
+			assert.Equal(t, tc.want, gotIOOut.String())
+		})
+	}
+}
@@ -19,6 +19,15 @@ func TestNewLogSubcommand(t *testing.T) {
 	assert.NotNil(t, NewLogSubcommand())
 }
 
+func TestNewLogSubcommand_SearchWithFiles(t *testing.T) {
+	command := NewLogSubcommand()
+	command.Flags().StringP("query", "q", "", "")
+	command.SetArgs([]string{"--search", "--files"})
+	command.SilenceUsage = true
+	command.SilenceErrors = true
+	assert.Equal(t, fmt.Errorf("--search and --files can't be used together"), command.Execute())
+}
+
 func TestNewLogCommand(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -161,3 +170,48 @@ func TestLogCli_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestPickCommit(t *testing.T) {
+	testCases := []struct {
+		name              string
+		runCommandWithFzf func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error)
+		want              string
+		wantIsErr         bool
+	}{
+		{
+			name: "picked",
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				assert.Equal(t, fmt.Sprintf("git log --color --oneline origin/master | fzf --multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --prompt 'bad> '", "git show --color  {1}", defaultFzfBindOption), commandLine)
+				return bytes.NewBufferString("abc Commit message1\n").Bytes(), nil
+			},
+			want:      "abc",
+			wantIsErr: false,
+		},
+		{
+			name: "canceled",
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, exec.Command("sh", "-c", "exit 130").Run()
+			},
+			want:      "",
+			wantIsErr: false,
+		},
+		{
+			name: "error",
+			runCommandWithFzf: func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) (i []byte, e error) {
+				return nil, errors.New("error")
+			},
+			want:      "",
+			wantIsErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandWithFzf = tc.runCommandWithFzf
+
+			got, gotErr := pickCommit(context.Background(), strings.NewReader("in"), &bytes.Buffer{}, []string{"origin/master"}, "bad")
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type notesCli struct {
+	ref        string
+	logOptions []string
+	fzfOption  string
+}
+
+const (
+	notesKeyAdd    = "alt-a"
+	notesKeyEdit   = "alt-e"
+	notesKeyAppend = "alt-p"
+	notesKeyRemove = "alt-d"
+)
+
+func NewNotesSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "notes [<git log options>]",
+		Short: "git notes with fzf",
+		Args:  cobra.MaximumNArgs(100),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			ref, err := flags.GetString("ref")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newNotesCli(ref, args, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("ref", "", "The notes ref like refs/notes/commits. Default: core.notesRef or refs/notes/commits")
+	return command
+}
+
+func newNotesCli(ref string, logOptions []string, fzfQuery string) (*notesCli, error) {
+	// git show shows notes of the default ref without --notes
+	previewCommand := "git show --color {1}"
+	if ref != "" {
+		previewCommand = fmt.Sprintf("git show --color --notes=%s {1}", ref)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --with-nth 2.. --expect %s,%s,%s,%s", fzfOption,
+		notesKeyAdd, notesKeyEdit, notesKeyAppend, notesKeyRemove)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &notesCli{
+		ref:        ref,
+		logOptions: logOptions,
+		fzfOption:  fzfOption,
+	}, nil
+}
+
+// notesArgs returns arguments of git notes with the notes ref
+func (c notesCli) notesArgs(args ...string) []string {
+	notesArgs := []string{"notes"}
+	if c.ref != "" {
+		notesArgs = append(notesArgs, "--ref", c.ref)
+	}
+	return append(notesArgs, args...)
+}
+
+func (c notesCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// The list is reloaded after each action
+	for {
+		list, err := c.listNotes(ctx)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			// fzf exits without any key for an empty list, so a note is added directly
+			if _, err := fmt.Fprintln(ioErr, "There is no note. Select a commit to add a note"); err != nil {
+				return fmt.Errorf("failed to output the message: %w", err)
+			}
+			ok, err := c.addNote(ctx, ioIn, ioErr, "add")
+			if err != nil || !ok {
+				return err
+			}
+			continue
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		commits := make([]string, 0, len(lines))
+		for _, line := range lines {
+			commits = append(commits, strings.SplitN(line, "\t", 2)[0])
+		}
+
+		switch key {
+		case notesKeyAdd:
+			if _, err := c.addNote(ctx, ioIn, ioErr, "add"); err != nil {
+				return err
+			}
+		case notesKeyAppend:
+			if _, err := c.addNote(ctx, ioIn, ioErr, "append"); err != nil {
+				return err
+			}
+		case notesKeyEdit:
+			for _, commit := range commits {
+				if err := runGitCommand(ctx, ioIn, ioErr, ioErr, c.notesArgs("edit", commit)...); err != nil {
+					return fmt.Errorf("failed to edit a note: %w", err)
+				}
+			}
+		case notesKeyRemove:
+			if len(commits) == 0 {
+				continue
+			}
+			ok, err := confirm(ioIn, ioErr, fmt.Sprintf("Remove notes of %s?", strings.Join(commits, ", ")))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, c.notesArgs(append([]string{"remove"}, commits...)...)...); err != nil {
+				return fmt.Errorf("failed to remove notes: %w", err)
+			}
+		default:
+			if len(commits) == 0 {
+				return nil
+			}
+			if _, err := io.WriteString(ioOut, strings.Join(commits, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// addNote runs git notes add or append for a commit picked by git fzf log.
+// false is returned if no commit is picked.
+func (c notesCli) addNote(ctx context.Context, ioIn io.Reader, ioErr io.Writer, action string) (bool, error) {
+	commit, err := pickCommit(ctx, ioIn, ioErr, c.logOptions, action+" a note")
+	if err != nil {
+		return false, err
+	}
+	if commit == "" {
+		return false, nil
+	}
+	// The message is written in the editor
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, c.notesArgs(action, commit)...); err != nil {
+		return false, fmt.Errorf("failed to %s a note: %w", action, err)
+	}
+	return true, nil
+}
+
+// listNotes lists commits which have notes like <commit><tab><short commit> <subject>
+func (c notesCli) listNotes(ctx context.Context) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, c.notesArgs("list")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	// Each line is like <note object><space><annotated object>
+	var objects []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			objects = append(objects, fields[1])
+		}
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	// A note can be added to an object other than a commit, like a blob. Each line is like <object><space><type>
+	var types bytes.Buffer
+	if err := runGitCommand(ctx, strings.NewReader(strings.Join(objects, "\n")+"\n"), &types, ioutil.Discard, "cat-file", "--batch-check=%(objectname) %(objecttype)"); err != nil {
+		return nil, fmt.Errorf("failed to get types of annotated objects: %w", err)
+	}
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(types.String()), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == "commit" {
+			commits = append(commits, fields[0])
+		}
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	// Commits are given by the standard input because there may be too many commits for arguments
+	var list bytes.Buffer
+	if err := runGitCommand(ctx, strings.NewReader(strings.Join(commits, "\n")+"\n"), &list, ioutil.Discard, "log", "--no-walk", "--stdin", "--color", "--format=%H%x09%C(yellow)%h%C(reset) %s"); err != nil {
+		return nil, fmt.Errorf("failed to get commits of notes: %w", err)
+	}
+	return list.Bytes(), nil
+}
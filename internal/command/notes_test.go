@@ -0,0 +1,327 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNotesSubcommand(t *testing.T) {
+	assert.NotNil(t, NewNotesSubcommand())
+}
+
+func TestNewNotesCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ref        string
+		logOptions []string
+		fzfQuery   string
+		envVars    map[string]string
+		want       *notesCli
+		wantErr    error
+	}{
+		{
+			name:       "no options",
+			ref:        "",
+			logOptions: []string{},
+			fzfQuery:   "",
+			want: &notesCli{
+				ref:        "",
+				logOptions: []string{},
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-a,alt-e,alt-p,alt-d", "git show --color {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "all options",
+			ref:        "refs/notes/review",
+			logOptions: []string{"origin/master"},
+			fzfQuery:   "fix",
+			want: &notesCli{
+				ref:        "refs/notes/review",
+				logOptions: []string{"origin/master"},
+				fzfOption:  fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-a,alt-e,alt-p,alt-d --query fix", "git show --color --notes=refs/notes/review {1}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:       "GIT_FZF_FZF_OPTION includes invalid env",
+			logOptions: []string{},
+			fzfQuery:   "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newNotesCli(tc.ref, tc.logOptions, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestNotesCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	list := "abc123\tabc Fix bug\ndef456\tdef Add feature\n"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		ioIn            string
+		wantErr         error
+		wantGitCommands []string
+		wantIO          string
+		wantIOErr       string
+	}{
+		{
+			name: "select commits",
+			fzfOutputs: []string{
+				"\nabc123\tabc Fix bug\ndef456\tdef Add feature\n",
+			},
+			wantErr: nil,
+			wantIO:  "abc123\ndef456\n",
+		},
+		{
+			name: "add a note to a picked commit",
+			fzfOutputs: []string{
+				"alt-a\n",
+				"xyz Other commit\n",
+				"\nabc123\tabc Fix bug\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"notes --ref refs/notes/review add xyz",
+			},
+			wantIO: "abc123\n",
+		},
+		{
+			name: "append a note to a picked commit",
+			fzfOutputs: []string{
+				"alt-p\nabc123\tabc Fix bug\n",
+				"abc Fix bug\n",
+				"\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"notes --ref refs/notes/review append abc",
+			},
+		},
+		{
+			name: "edit notes",
+			fzfOutputs: []string{
+				"alt-e\nabc123\tabc Fix bug\ndef456\tdef Add feature\n",
+				"\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"notes --ref refs/notes/review edit abc123",
+				"notes --ref refs/notes/review edit def456",
+			},
+		},
+		{
+			name: "remove notes",
+			fzfOutputs: []string{
+				"alt-d\nabc123\tabc Fix bug\ndef456\tdef Add feature\n",
+				"\n",
+			},
+			ioIn:    "y\n",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"notes --ref refs/notes/review remove abc123 def456",
+			},
+			wantIOErr: "Remove notes of abc123, def456? (y/N): ",
+		},
+		{
+			name: "remove is canceled",
+			fzfOutputs: []string{
+				"alt-d\nabc123\tabc Fix bug\n",
+				"\n",
+			},
+			ioIn:      "n\n",
+			wantErr:   nil,
+			wantIOErr: "Remove notes of abc123? (y/N): ",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := notesCli{
+				ref:        "refs/notes/review",
+				logOptions: []string{"origin/master"},
+				fzfOption:  fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "notes --ref refs/notes/review list":
+					return []byte("n1 abc123\nn2 def456\nn3 789abc\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				if strings.HasPrefix(commandLine, "fzf ") {
+					assert.Equal(t, "fzf "+fzfOption, commandLine)
+					gotList, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, list, string(gotList))
+				} else {
+					assert.True(t, strings.HasPrefix(commandLine, "git log --color --oneline origin/master | fzf "), commandLine)
+				}
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				command := strings.Join(args, " ")
+				switch command {
+				case "cat-file --batch-check=%(objectname) %(objecttype)":
+					in, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, "abc123\ndef456\n789abc\n", string(in))
+					_, err = io.WriteString(ioOut, "abc123 commit\ndef456 commit\n789abc blob\n")
+					return err
+				case "log --no-walk --stdin --color --format=%H%x09%C(yellow)%h%C(reset) %s":
+					in, err := ioutil.ReadAll(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, "abc123\ndef456\n", string(in))
+					_, err = io.WriteString(ioOut, list)
+					return err
+				}
+				gotGitCommands = append(gotGitCommands, command)
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestNotesCli_ListNotes(t *testing.T) {
+	sut := notesCli{}
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		assert.Equal(t, "notes list", strings.Join(args, " "))
+		return []byte(""), nil
+	}
+	got, err := sut.listNotes(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestNotesCli_Run_NoNote(t *testing.T) {
+	sut := notesCli{
+		logOptions: []string{},
+		fzfOption:  "--inline-info",
+	}
+
+	var notes []string
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "notes list":
+			return []byte(strings.Join(notes, "\n")), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	}
+	fzfOutputs := []string{
+		"abc Fix bug\n",
+		"\nabc123\tabc Fix bug\n",
+	}
+	var gotFzfCommands []string
+	runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+		gotFzfCommands = append(gotFzfCommands, commandLine)
+		require.True(t, len(gotFzfCommands) <= len(fzfOutputs))
+		return []byte(fzfOutputs[len(gotFzfCommands)-1]), nil
+	}
+	var gotGitCommands []string
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		command := strings.Join(args, " ")
+		switch command {
+		case "cat-file --batch-check=%(objectname) %(objecttype)":
+			_, err := io.WriteString(ioOut, "abc123 commit\n")
+			return err
+		case "log --no-walk --stdin --color --format=%H%x09%C(yellow)%h%C(reset) %s":
+			_, err := io.WriteString(ioOut, "abc123\tabc Fix bug\n")
+			return err
+		}
+		gotGitCommands = append(gotGitCommands, command)
+		notes = append(notes, "n1 abc123")
+		return nil
+	}
+
+	var gotIOOut bytes.Buffer
+	var gotIOErr bytes.Buffer
+	require.NoError(t, sut.Run(context.Background(), strings.NewReader(""), &gotIOOut, &gotIOErr))
+	assert.Equal(t, 2, len(gotFzfCommands))
+	assert.True(t, strings.HasPrefix(gotFzfCommands[0], "git log --color --oneline  | fzf "), gotFzfCommands[0])
+	assert.Equal(t, "fzf --inline-info", gotFzfCommands[1])
+	assert.Equal(t, []string{"notes add abc"}, gotGitCommands)
+	assert.Equal(t, "abc123\n", gotIOOut.String())
+	assert.Equal(t, "There is no note. Select a commit to add a note\n", gotIOErr.String())
+}
+
+func TestNotesCli_ListNotes_NoCommit(t *testing.T) {
+	sut := notesCli{}
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		assert.Equal(t, "notes list", strings.Join(args, " "))
+		return []byte("n1 789abc\n"), nil
+	}
+	var gotGitCommands []string
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+		_, err := io.WriteString(ioOut, "789abc tree\n")
+		return err
+	}
+	got, err := sut.listNotes(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Equal(t, []string{"cat-file --batch-check=%(objectname) %(objecttype)"}, gotGitCommands)
+}
@@ -0,0 +1,409 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type rebaseTodoCli struct {
+	path        string
+	listCommand string
+	fzfOption   string
+}
+
+type rebaseTodoEntry struct {
+	command string
+	// arguments are the rest of the line, e.g. <commit> <subject> of pick
+	arguments string
+}
+
+type rebaseTodo struct {
+	entries []rebaseTodoEntry
+	// comments are written after the entries
+	comments []string
+}
+
+const (
+	rebaseTodoMoveUp   = "up"
+	rebaseTodoMoveDown = "down"
+)
+
+var (
+	rebaseTodoCommandAbbreviations = map[string]string{
+		"p": "pick",
+		"r": "reword",
+		"e": "edit",
+		"s": "squash",
+		"f": "fixup",
+		"x": "exec",
+		"b": "break",
+		"d": "drop",
+		"l": "label",
+		"t": "reset",
+		"m": "merge",
+		"u": "update-ref",
+	}
+	rebaseTodoCommands = map[string]bool{
+		"pick":       true,
+		"reword":     true,
+		"edit":       true,
+		"squash":     true,
+		"fixup":      true,
+		"exec":       true,
+		"break":      true,
+		"drop":       true,
+		"label":      true,
+		"reset":      true,
+		"merge":      true,
+		"update-ref": true,
+		"noop":       true,
+	}
+	// rebaseTodoCommitCommands are commands which can be switched with each other
+	rebaseTodoCommitCommands = map[string]bool{
+		"pick":   true,
+		"reword": true,
+		"edit":   true,
+		"squash": true,
+		"fixup":  true,
+		"drop":   true,
+	}
+)
+
+func NewRebaseTodoSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "rebase-todo <file>",
+		Short: "Edit the todo list of git rebase --interactive with fzf, used as GIT_SEQUENCE_EDITOR",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			isList, err := flags.GetBool("list")
+			if err != nil {
+				return err
+			}
+			command, err := flags.GetString("set")
+			if err != nil {
+				return err
+			}
+			direction, err := flags.GetString("move")
+			if err != nil {
+				return err
+			}
+			path := args[0]
+
+			switch {
+			case isList:
+				return listRebaseTodo(os.Stdout, path)
+			case command != "":
+				return setRebaseTodoCommand(path, command, args[1:])
+			case direction != "":
+				return moveRebaseTodoEntry(path, direction, args[1:])
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newRebaseTodoCli(path, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.Bool("list", false, "Only print the entries of the todo list for fzf")
+	flags.String("set", "", "Set the command of the entries at the given indexes")
+	flags.String("move", "", "Move the entry at the given index up or down")
+	_ = flags.MarkHidden("list")
+	_ = flags.MarkHidden("set")
+	_ = flags.MarkHidden("move")
+	return command
+}
+
+func newRebaseTodoCli(path string, fzfQuery string) (*rebaseTodoCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	// The command and the path are quoted again in the bind option of fzf
+	listCommand := rebaseTodoListCommand(self, path, shellQuote)
+	bindListCommand := rebaseTodoListCommand(self, path, shellQuoteInFzfOption)
+	bindSelf := shellQuoteInFzfOption(self)
+	bindPath := shellQuoteInFzfOption(path)
+
+	// The same preview as git fzf log
+	previewCommand, err := commandFromTemplate("preview", logFzfPreviewCommand, map[string]interface{}{
+		"path":        "{3}",
+		"objectRange": "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	// The bind option isn't built by a template because + is escaped
+	setCommand := func(key string, command string) string {
+		return fmt.Sprintf("%s:execute-silent(%s rebase-todo %s {+1} --set %s)+reload(%s)", key, bindSelf, bindPath, command, bindListCommand)
+	}
+	moveEntry := func(key string, direction string) string {
+		return fmt.Sprintf("%s:execute-silent(%s rebase-todo %s {1} --move %s)+reload(%s)+%s", key, bindSelf, bindPath, direction, bindListCommand, direction)
+	}
+	bindOption := strings.Join([]string{
+		setCommand("alt-p", "pick"),
+		setCommand("alt-r", "reword"),
+		setCommand("alt-e", "edit"),
+		setCommand("alt-s", "squash"),
+		setCommand("alt-f", "fixup"),
+		setCommand("alt-d", "drop"),
+		moveEntry("alt-k", rebaseTodoMoveUp),
+		moveEntry("alt-j", rebaseTodoMoveDown),
+	}, ",")
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --no-sort --delimiter '\\t' --with-nth 2.. --bind '" + bindOption + "'"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &rebaseTodoCli{
+		path:        path,
+		listCommand: listCommand,
+		fzfOption:   fzfOption,
+	}, nil
+}
+
+// rebaseTodoListCommand returns the command to list entries of the todo list, whose arguments are quoted by the quote function
+func rebaseTodoListCommand(self string, path string, quote func(string) string) string {
+	return fmt.Sprintf("%s rebase-todo --list %s", quote(self), quote(path))
+}
+
+func (c rebaseTodoCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	original, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read the todo list: %w", err)
+	}
+
+	command := fmt.Sprintf("%s | fzf %s", c.listCommand, c.fzfOption)
+	for {
+		if _, err := runCommandWithFzf(ctx, command, ioIn, ioErr); err != nil {
+			// The todo list is updated by key bindings, so it's restored if it's canceled
+			if writeErr := ioutil.WriteFile(c.path, original, 0644); writeErr != nil {
+				return fmt.Errorf("failed to restore the todo list: %w", writeErr)
+			}
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+
+		todo, err := readRebaseTodo(c.path)
+		if err != nil {
+			return err
+		}
+		err = todo.validate()
+		if err == nil {
+			return nil
+		}
+		if _, err := fmt.Fprintf(ioErr, "The todo list is invalid: %s\n", err); err != nil {
+			return fmt.Errorf("failed to output the error: %w", err)
+		}
+	}
+}
+
+func listRebaseTodo(ioOut io.Writer, path string) error {
+	todo, err := readRebaseTodo(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, entry := range todo.entries {
+		commit := ""
+		description := entry.arguments
+		if rebaseTodoCommitCommands[entry.command] {
+			arguments := strings.SplitN(entry.commitArguments(), " ", 2)
+			commit = arguments[0]
+			description = ""
+			if len(arguments) == 2 {
+				description = arguments[1]
+			}
+		}
+		fmt.Fprintf(&buf, "%d\t%s\t%s\t%s\n", i, entry.command, commit, description)
+	}
+	if _, err := ioOut.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+func setRebaseTodoCommand(path string, command string, indexes []string) error {
+	if !rebaseTodoCommitCommands[command] {
+		return fmt.Errorf("unsupported command: %s", command)
+	}
+	todo, err := readRebaseTodo(path)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		i, err := parseRebaseTodoIndex(index, len(todo.entries))
+		if err != nil {
+			return err
+		}
+		entry := todo.entries[i]
+		if !rebaseTodoCommitCommands[entry.command] {
+			continue
+		}
+		// fixup -C <commit> is changed into <command> <commit>
+		todo.entries[i] = rebaseTodoEntry{
+			command:   command,
+			arguments: entry.commitArguments(),
+		}
+	}
+	return todo.write(path)
+}
+
+func moveRebaseTodoEntry(path string, direction string, indexes []string) error {
+	if len(indexes) != 1 {
+		return errors.New("only one entry can be moved")
+	}
+	todo, err := readRebaseTodo(path)
+	if err != nil {
+		return err
+	}
+	i, err := parseRebaseTodoIndex(indexes[0], len(todo.entries))
+	if err != nil {
+		return err
+	}
+
+	var j int
+	switch direction {
+	case rebaseTodoMoveUp:
+		j = i - 1
+	case rebaseTodoMoveDown:
+		j = i + 1
+	default:
+		return fmt.Errorf("invalid direction: %s", direction)
+	}
+	if j < 0 || j >= len(todo.entries) {
+		return nil
+	}
+	todo.entries[i], todo.entries[j] = todo.entries[j], todo.entries[i]
+	return todo.write(path)
+}
+
+func parseRebaseTodoIndex(index string, numEntries int) (int, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index of the todo list: %s: %w", index, err)
+	}
+	if i < 0 || i >= numEntries {
+		return 0, fmt.Errorf("index out of the todo list: %d", i)
+	}
+	return i, nil
+}
+
+func readRebaseTodo(path string) (*rebaseTodo, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the todo list: %w", err)
+	}
+	return parseRebaseTodo(string(content)), nil
+}
+
+func parseRebaseTodo(content string) *rebaseTodo {
+	todo := &rebaseTodo{}
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			todo.comments = append(todo.comments, line)
+			continue
+		}
+		fields := strings.SplitN(trimmed, " ", 2)
+		command := fields[0]
+		if fullCommand, ok := rebaseTodoCommandAbbreviations[command]; ok {
+			command = fullCommand
+		}
+		arguments := ""
+		if len(fields) == 2 {
+			arguments = strings.TrimSpace(fields[1])
+		}
+		todo.entries = append(todo.entries, rebaseTodoEntry{
+			command:   command,
+			arguments: arguments,
+		})
+	}
+	return todo
+}
+
+// commitArguments returns the arguments without the options like -C of fixup
+func (e rebaseTodoEntry) commitArguments() string {
+	if e.command == "fixup" && (strings.HasPrefix(e.arguments, "-C ") || strings.HasPrefix(e.arguments, "-c ")) {
+		return strings.TrimSpace(e.arguments[3:])
+	}
+	return e.arguments
+}
+
+func (e rebaseTodoEntry) String() string {
+	if e.arguments == "" {
+		return e.command
+	}
+	return e.command + " " + e.arguments
+}
+
+// validate checks the commands and if squash or fixup has a previous commit to be melded into
+func (t rebaseTodo) validate() error {
+	if err := t.validateCommands(); err != nil {
+		return err
+	}
+	hasCommit := false
+	for _, entry := range t.entries {
+		switch entry.command {
+		case "pick", "reword", "edit", "merge":
+			hasCommit = true
+		case "squash", "fixup":
+			if !hasCommit {
+				return fmt.Errorf("cannot %s without a previous commit: %s", entry.command, entry)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCommands checks only the commands because the order may be invalid while a user edits the todo list
+func (t rebaseTodo) validateCommands() error {
+	for _, entry := range t.entries {
+		if !rebaseTodoCommands[entry.command] {
+			return fmt.Errorf("unknown command: %s", entry)
+		}
+	}
+	return nil
+}
+
+func (t rebaseTodo) write(path string) error {
+	if err := t.validateCommands(); err != nil {
+		return err
+	}
+	var builder strings.Builder
+	for _, entry := range t.entries {
+		builder.WriteString(entry.String() + "\n")
+	}
+	for _, comment := range t.comments {
+		builder.WriteString(comment + "\n")
+	}
+	if err := ioutil.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write the todo list: %w", err)
+	}
+	return nil
+}
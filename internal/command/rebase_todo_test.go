@@ -0,0 +1,348 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRebaseTodo = `pick 6e19be8 c1
+pick 9520359 c2
+fixup -C f4964fa c3
+exec make test
+
+# Rebase 2684229..f4964fa onto 2684229 (4 commands)
+#
+# Commands:
+`
+
+func writeTestRebaseTodo(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "git-fzf")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "git-rebase-todo")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func readTestRebaseTodo(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestNewRebaseTodoSubcommand(t *testing.T) {
+	assert.NotNil(t, NewRebaseTodoSubcommand())
+}
+
+func TestNewRebaseTodoCommand(t *testing.T) {
+	// path is quoted in the bind option
+	bindOption := func(path string) string {
+		return strings.Join([]string{
+			fmt.Sprintf(`alt-p:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set pick)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-r:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set reword)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-e:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set edit)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-s:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set squash)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-f:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set fixup)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-d:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {+1} --set drop)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)`, path),
+			fmt.Sprintf(`alt-k:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {1} --move up)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)+up`, path),
+			fmt.Sprintf(`alt-j:execute-silent('\''git-fzf'\'' rebase-todo %[1]s {1} --move down)+reload('\''git-fzf'\'' rebase-todo --list %[1]s)+down`, path),
+		}, ",")
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *rebaseTodoCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			path:     "todo",
+			fzfQuery: "",
+			want: &rebaseTodoCli{
+				path:        "todo",
+				listCommand: "'git-fzf' rebase-todo --list 'todo'",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-sort --delimiter '\\t' --with-nth 2.. --bind '%s'", "git show --color  {3}", defaultFzfBindOption, bindOption(`'\''todo'\''`)),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			path:     "/my repo/.git/rebase-merge/git-rebase-todo",
+			fzfQuery: "fix",
+			want: &rebaseTodoCli{
+				path:        "/my repo/.git/rebase-merge/git-rebase-todo",
+				listCommand: "'git-fzf' rebase-todo --list '/my repo/.git/rebase-merge/git-rebase-todo'",
+				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-sort --delimiter '\\t' --with-nth 2.. --bind '%s' --query fix", "git show --color  {3}", defaultFzfBindOption, bindOption(`'\''/my repo/.git/rebase-merge/git-rebase-todo'\''`)),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			path:     "todo",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newRebaseTodoCli(tc.path, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestRebaseTodoCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name string
+		// edits are the contents written by key bindings in each run of fzf
+		edits       []string
+		fzfErr      error
+		wantErr     error
+		wantContent string
+		wantIOErr   string
+	}{
+		{
+			name: "edited",
+			edits: []string{
+				"reword 6e19be8 c1\n",
+			},
+			wantErr:     nil,
+			wantContent: "reword 6e19be8 c1\n",
+			wantIOErr:   "",
+		},
+		{
+			name: "invalid and then edited",
+			edits: []string{
+				"fixup 6e19be8 c1\n",
+				"pick 6e19be8 c1\n",
+			},
+			wantErr:     nil,
+			wantContent: "pick 6e19be8 c1\n",
+			wantIOErr:   "The todo list is invalid: cannot fixup without a previous commit: fixup 6e19be8 c1\n",
+		},
+		{
+			name:        "command with fzf error",
+			fzfErr:      defaultWantErr,
+			wantErr:     defaultWantErr,
+			wantContent: testRebaseTodo,
+			wantIOErr:   "",
+		},
+		{
+			name:        "command with fzf exit error (not 130)",
+			fzfErr:      &exitErr,
+			wantErr:     &exitErr,
+			wantContent: testRebaseTodo,
+			wantIOErr:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestRebaseTodo(t, testRebaseTodo)
+			defer os.RemoveAll(filepath.Dir(path))
+			sut := rebaseTodoCli{
+				path:        path,
+				listCommand: "git-fzf rebase-todo --list " + path,
+				fzfOption:   fzfOption,
+			}
+
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, fmt.Sprintf("git-fzf rebase-todo --list %s | fzf %s", path, fzfOption), commandLine)
+				if tc.fzfErr != nil {
+					require.NoError(t, ioutil.WriteFile(path, []byte("drop 6e19be8 c1\n"), 0644))
+					return nil, tc.fzfErr
+				}
+				require.NoError(t, ioutil.WriteFile(path, []byte(tc.edits[fzfCount]), 0644))
+				fzfCount++
+				return []byte("0\tpick\t6e19be8\tc1\n"), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantContent, readTestRebaseTodo(t, path))
+			assert.Equal(t, "", gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestListRebaseTodo(t *testing.T) {
+	path := writeTestRebaseTodo(t, testRebaseTodo)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var gotIOOut bytes.Buffer
+	require.NoError(t, listRebaseTodo(&gotIOOut, path))
+	assert.Equal(t, "0\tpick\t6e19be8\tc1\n1\tpick\t9520359\tc2\n2\tfixup\tf4964fa\tc3\n3\texec\t\tmake test\n", gotIOOut.String())
+}
+
+func TestSetRebaseTodoCommand(t *testing.T) {
+	testCases := []struct {
+		name        string
+		command     string
+		indexes     []string
+		wantContent string
+		wantIsErr   bool
+	}{
+		{
+			name:        "multiple entries",
+			command:     "squash",
+			indexes:     []string{"1", "2", "3"},
+			wantContent: strings.Replace(strings.Replace(testRebaseTodo, "pick 9520359", "squash 9520359", 1), "fixup -C f4964fa", "squash f4964fa", 1),
+			wantIsErr:   false,
+		},
+		{
+			name:        "abbreviation is not supported",
+			command:     "p",
+			indexes:     []string{"0"},
+			wantContent: testRebaseTodo,
+			wantIsErr:   true,
+		},
+		{
+			name:        "out of the todo list",
+			command:     "drop",
+			indexes:     []string{"4"},
+			wantContent: testRebaseTodo,
+			wantIsErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestRebaseTodo(t, testRebaseTodo)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			gotErr := setRebaseTodoCommand(path, tc.command, tc.indexes)
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+			assert.Equal(t, tc.wantContent, readTestRebaseTodo(t, path))
+		})
+	}
+}
+
+func TestMoveRebaseTodoEntry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		direction   string
+		indexes     []string
+		wantContent string
+		wantIsErr   bool
+	}{
+		{
+			name:        "up",
+			direction:   "up",
+			indexes:     []string{"1"},
+			wantContent: "pick 9520359 c2\npick 6e19be8 c1\n" + testRebaseTodo[len("pick 6e19be8 c1\npick 9520359 c2\n"):],
+			wantIsErr:   false,
+		},
+		{
+			name:        "down",
+			direction:   "down",
+			indexes:     []string{"0"},
+			wantContent: "pick 9520359 c2\npick 6e19be8 c1\n" + testRebaseTodo[len("pick 6e19be8 c1\npick 9520359 c2\n"):],
+			wantIsErr:   false,
+		},
+		{
+			name:        "top entry cannot be moved up",
+			direction:   "up",
+			indexes:     []string{"0"},
+			wantContent: testRebaseTodo,
+			wantIsErr:   false,
+		},
+		{
+			name:        "multiple entries",
+			direction:   "up",
+			indexes:     []string{"1", "2"},
+			wantContent: testRebaseTodo,
+			wantIsErr:   true,
+		},
+		{
+			name:        "invalid direction",
+			direction:   "left",
+			indexes:     []string{"1"},
+			wantContent: testRebaseTodo,
+			wantIsErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestRebaseTodo(t, testRebaseTodo)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			gotErr := moveRebaseTodoEntry(path, tc.direction, tc.indexes)
+			assert.Equal(t, tc.wantIsErr, gotErr != nil)
+			assert.Equal(t, tc.wantContent, readTestRebaseTodo(t, path))
+		})
+	}
+}
+
+func TestRebaseTodo_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			content: testRebaseTodo,
+			wantErr: nil,
+		},
+		{
+			name:    "abbreviations",
+			content: "p 6e19be8 c1\ns 9520359 c2\nx make test\n",
+			wantErr: nil,
+		},
+		{
+			name:    "squash without a previous commit",
+			content: "drop 6e19be8 c1\nsquash 9520359 c2\n",
+			wantErr: errors.New("cannot squash without a previous commit: squash 9520359 c2"),
+		},
+		{
+			name:    "unknown command",
+			content: "pick 6e19be8 c1\nrevert 9520359 c2\n",
+			wantErr: errors.New("unknown command: revert 9520359 c2"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantErr, parseRebaseTodo(tc.content).validate())
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type refCli struct {
+	types     []string
+	fullName  bool
+	fzfOption string
+	// first is the short name of a ref listed first to be picked without any query
+	first string
+}
+
+const (
+	refTypeHead   = "head"
+	refTypeBranch = "branch"
+	refTypeRemote = "remote"
+	refTypeTag    = "tag"
+	refTypeStash  = "stash"
+	refTypeReflog = "reflog"
+
+	refFzfPreviewCommand = "git log --color --oneline --graph -30 {{.ref}}"
+
+	refReflogCount = 20
+)
+
+var (
+	refTypes = []string{refTypeHead, refTypeBranch, refTypeRemote, refTypeTag, refTypeStash, refTypeReflog}
+	// Special refs in the git directory, which are listed only if they exist
+	refHeadNames = []string{"HEAD", "ORIG_HEAD", "FETCH_HEAD"}
+)
+
+func NewRefSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "ref",
+		Short: "Select a ref with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			types, err := flags.GetStringSlice("types")
+			if err != nil {
+				return err
+			}
+			fullName, err := flags.GetBool("full-name")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newRefCli(types, fullName, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.StringSliceP("types", "t", refTypes, "The types of refs to list: "+strings.Join(refTypes, ", "))
+	flags.Bool("full-name", false, "Print the full name of a ref like refs/heads/master instead of the short name")
+	return command
+}
+
+func newRefCli(types []string, fullName bool, fzfQuery string) (*refCli, error) {
+	for _, t := range types {
+		if !containsString(refTypes, t) {
+			return nil, fmt.Errorf("invalid type of refs: %s", t)
+		}
+	}
+
+	previewCommand, err := commandFromTemplate("preview", refFzfPreviewCommand, map[string]interface{}{
+		"ref": "{3}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	// Only one ref is selected to be used in a command substitution
+	fzfOption = fzfOption + " --no-multi --delimiter '\\t' --with-nth 1,2,4"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &refCli{
+		types:     types,
+		fullName:  fullName,
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c refCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	list, err := listRefs(ctx, c.types, c.first)
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("fzf %s", c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	fields := strings.Split(strings.TrimRight(string(out), "\n"), "\t")
+	if len(fields) < 3 {
+		return nil
+	}
+	name := fields[1]
+	if c.fullName {
+		name = fields[2]
+	}
+	if _, err := io.WriteString(ioOut, name+"\n"); err != nil {
+		return fmt.Errorf("failed to output the result: %w", err)
+	}
+	return nil
+}
+
+// listRefs lists refs of the types like <type><tab><short name><tab><full name><tab><subject>.
+// The ref of the short name first is listed first if it's given.
+func listRefs(ctx context.Context, types []string, first string) ([]byte, error) {
+	var lines []string
+	for _, t := range refTypes {
+		if !containsString(types, t) {
+			continue
+		}
+		typeLines, err := listRefsOfType(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, typeLines...)
+	}
+
+	var list bytes.Buffer
+	for i, line := range lines {
+		if first != "" && strings.SplitN(line, "\t", 3)[1] == first {
+			list.WriteString(line + "\n")
+			lines = append(lines[:i:i], lines[i+1:]...)
+			break
+		}
+	}
+	for _, line := range lines {
+		list.WriteString(line + "\n")
+	}
+	return list.Bytes(), nil
+}
+
+func listRefsOfType(ctx context.Context, refType string) ([]string, error) {
+	var lines []string
+	switch refType {
+	case refTypeHead:
+		for _, name := range refHeadNames {
+			out, err := runGitCommandOutput(ctx, "log", "-1", "--format=%s", name)
+			if err != nil {
+				// The ref doesn't exist
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s", refType, name, name, strings.TrimSpace(string(out))))
+		}
+		return lines, nil
+	case refTypeStash, refTypeReflog:
+		args := []string{"stash", "list", "--format=%gd%x09%s"}
+		if refType == refTypeReflog {
+			args = []string{"reflog", "-n", fmt.Sprint(refReflogCount), "--format=%gd%x09%gs"}
+		}
+		out, err := runGitCommandOutput(ctx, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", refType, err)
+		}
+		// Each line is like <name><tab><subject>
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if fields := strings.SplitN(line, "\t", 2); len(fields) == 2 {
+				lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s", refType, fields[0], fields[0], fields[1]))
+			}
+		}
+		return lines, nil
+	}
+
+	pattern := map[string]string{
+		refTypeBranch: "refs/heads",
+		refTypeRemote: "refs/remotes",
+		refTypeTag:    "refs/tags",
+	}[refType]
+	out, err := runGitCommandOutput(ctx, "for-each-ref", "--format=%(refname:short)%09%(refname)%09%(subject)", pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			lines = append(lines, refType+"\t"+line)
+		}
+	}
+	return lines, nil
+}
+
+// pickRef returns the short name of a ref picked by git fzf ref, or an empty string if it's canceled.
+// first is listed first to be picked without any query.
+func pickRef(ctx context.Context, ioErr io.Writer, prompt string, first string) (string, error) {
+	cli, err := newRefCli([]string{refTypeHead, refTypeBranch, refTypeRemote, refTypeTag}, false, "")
+	if err != nil {
+		return "", err
+	}
+	cli.fzfOption = fmt.Sprintf("%s --prompt '%s> '", cli.fzfOption, prompt)
+	cli.first = first
+
+	var out bytes.Buffer
+	if err := cli.Run(ctx, nil, &out, ioErr); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRefSubcommand(t *testing.T) {
+	assert.NotNil(t, NewRefSubcommand())
+}
+
+func TestNewRefCommand(t *testing.T) {
+	previewCommand := "git log --color --oneline --graph -30 {3}"
+
+	testCases := []struct {
+		name     string
+		types    []string
+		fullName bool
+		fzfQuery string
+		envVars  map[string]string
+		want     *refCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			types:    refTypes,
+			fullName: false,
+			fzfQuery: "",
+			want: &refCli{
+				types:     refTypes,
+				fullName:  false,
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --delimiter '\\t' --with-nth 1,2,4", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			types:    []string{"branch", "tag"},
+			fullName: true,
+			fzfQuery: "master",
+			want: &refCli{
+				types:     []string{"branch", "tag"},
+				fullName:  true,
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi --delimiter '\\t' --with-nth 1,2,4 --query master", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "invalid type",
+			types:   []string{"branch", "note"},
+			want:    nil,
+			wantErr: errors.New("invalid type of refs: note"),
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			types:    refTypes,
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newRefCli(tc.types, tc.fullName, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestRefCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name      string
+		types     []string
+		fullName  bool
+		first     string
+		fzfOutput string
+		fzfErr    error
+		wantList  string
+		wantErr   error
+		wantIO    string
+	}{
+		{
+			name:      "all types",
+			types:     refTypes,
+			fzfOutput: "branch\tmaster\trefs/heads/master\tFix bug\n",
+			wantList: "head\tHEAD\tHEAD\tFix bug\nhead\tORIG_HEAD\tORIG_HEAD\tAdd feature\n" +
+				"branch\tmaster\trefs/heads/master\tFix bug\n" +
+				"remote\torigin/master\trefs/remotes/origin/master\tAdd feature\n" +
+				"tag\tv1.0\trefs/tags/v1.0\tRelease\n" +
+				"stash\tstash@{0}\tstash@{0}\tWIP on master: abc Fix bug\n" +
+				"reflog\tHEAD@{0}\tHEAD@{0}\tcommit: Fix bug\nreflog\tHEAD@{1}\tHEAD@{1}\tcheckout: moving from feature to master\n",
+			wantErr: nil,
+			wantIO:  "master\n",
+		},
+		{
+			name:      "filtered types with the full name",
+			types:     []string{"tag", "branch"},
+			fullName:  true,
+			fzfOutput: "tag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			wantList:  "branch\tmaster\trefs/heads/master\tFix bug\ntag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			wantErr:   nil,
+			wantIO:    "refs/tags/v1.0\n",
+		},
+		{
+			name:      "the first ref",
+			types:     []string{"branch", "tag"},
+			first:     "v1.0",
+			fzfOutput: "tag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			wantList:  "tag\tv1.0\trefs/tags/v1.0\tRelease\nbranch\tmaster\trefs/heads/master\tFix bug\n",
+			wantErr:   nil,
+			wantIO:    "v1.0\n",
+		},
+		{
+			name:     "command with fzf error",
+			types:    []string{"tag"},
+			fzfErr:   defaultWantErr,
+			wantList: "tag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			wantErr:  defaultWantErr,
+		},
+		{
+			name:     "command with fzf exit error (not 130)",
+			types:    []string{"tag"},
+			fzfErr:   &exitErr,
+			wantList: "tag\tv1.0\trefs/tags/v1.0\tRelease\n",
+			wantErr:  &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := refCli{
+				types:     tc.types,
+				fullName:  tc.fullName,
+				fzfOption: fzfOption,
+				first:     tc.first,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "log -1 --format=%s HEAD":
+					return []byte("Fix bug\n"), nil
+				case "log -1 --format=%s ORIG_HEAD":
+					return []byte("Add feature\n"), nil
+				case "log -1 --format=%s FETCH_HEAD":
+					return nil, errors.New("unknown revision")
+				case "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/heads":
+					return []byte("master\trefs/heads/master\tFix bug\n"), nil
+				case "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/remotes":
+					return []byte("origin/master\trefs/remotes/origin/master\tAdd feature\n"), nil
+				case "for-each-ref --format=%(refname:short)%09%(refname)%09%(subject) refs/tags":
+					return []byte("v1.0\trefs/tags/v1.0\tRelease\n"), nil
+				case "stash list --format=%gd%x09%s":
+					return []byte("stash@{0}\tWIP on master: abc Fix bug\n"), nil
+				case "reflog -n 20 --format=%gd%x09%gs":
+					return []byte("HEAD@{0}\tcommit: Fix bug\nHEAD@{1}\tcheckout: moving from feature to master\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				list, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantList, string(list))
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				return []byte(tc.fzfOutput), nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
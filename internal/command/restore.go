@@ -0,0 +1,190 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type restoreCli struct {
+	fzfOption string
+}
+
+const (
+	// Paths of git status are relative to the top directory
+	restoreFzfPreviewCommand = `cd "$(git rev-parse --show-toplevel)" && git diff --color --cached -- {{.path}} && git diff --color -- {{.path}}`
+
+	restoreKeyWorktree = "alt-w"
+	restoreKeyUnstage  = "alt-u"
+	restoreKeySource   = "alt-s"
+
+	restoreBackupMessage = "git fzf restore backup"
+)
+
+func NewRestoreSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore",
+		Short: "git restore for files selected with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newRestoreCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newRestoreCli(fzfQuery string) (*restoreCli, error) {
+	previewCommand, err := commandFromTemplate("preview", restoreFzfPreviewCommand, map[string]interface{}{
+		"path": "{2}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --expect %s,%s,%s", fzfOption, restoreKeyWorktree, restoreKeyUnstage, restoreKeySource)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &restoreCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c restoreCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// Paths of git status are relative to the top directory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The list is reloaded after each action
+	for {
+		list, err := listRestoreFiles(ctx)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			_, err := fmt.Fprintln(ioErr, "There is no change to restore")
+			return err
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		paths := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if fields := strings.SplitN(line, "\t", 2); len(fields) == 2 {
+				paths = append(paths, fields[1])
+			}
+		}
+		if len(paths) == 0 {
+			return nil
+		}
+
+		switch key {
+		case restoreKeyWorktree:
+			if err := restoreFiles(ctx, ioIn, ioErr, top, "Discard changes of the working tree", []string{"--worktree"}, paths); err != nil {
+				return err
+			}
+		case restoreKeyUnstage:
+			// The changes are kept in the working tree
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "restore", "--staged", "--"}, paths...)...); err != nil {
+				return fmt.Errorf("failed to unstage files: %w", err)
+			}
+		case restoreKeySource:
+			source, err := pickCommit(ctx, ioIn, ioErr, []string{}, "source")
+			if err != nil {
+				return err
+			}
+			if source == "" {
+				continue
+			}
+			message := fmt.Sprintf("Restore the index and the working tree from %s", source)
+			if err := restoreFiles(ctx, ioIn, ioErr, top, message, []string{"--source=" + source, "--staged", "--worktree"}, paths); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(paths, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// restoreFiles runs git restore after a user confirms it, and saves the current changes in the stash list
+// so that the discarded changes can be recovered by git stash apply. Paths are relative to the top directory.
+func restoreFiles(ctx context.Context, ioIn io.Reader, ioErr io.Writer, top string, message string, options []string, paths []string) error {
+	ok, err := confirm(ioIn, ioErr, fmt.Sprintf("%s for %s?", message, strings.Join(paths, ", ")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	out, err := runGitCommandOutput(ctx, "stash", "create", restoreBackupMessage)
+	if err != nil {
+		return fmt.Errorf("failed to back up the changes: %w", err)
+	}
+	// git stash create prints nothing if there is no local change
+	if backup := strings.TrimSpace(string(out)); backup != "" {
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "stash", "store", "--message", restoreBackupMessage, backup); err != nil {
+			return fmt.Errorf("failed to back up the changes: %w", err)
+		}
+		if _, err := fmt.Fprintf(ioErr, "The changes before restore are saved as stash@{0} (%s)\n", backup); err != nil {
+			return fmt.Errorf("failed to output the result: %w", err)
+		}
+	}
+
+	args := append([]string{"-C", top, "restore"}, options...)
+	args = append(args, "--")
+	args = append(args, paths...)
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+		return fmt.Errorf("failed to restore files: %w", err)
+	}
+	return nil
+}
+
+// listRestoreFiles lists changed files except untracked files, which git restore cannot restore
+func listRestoreFiles(ctx context.Context) ([]byte, error) {
+	list, err := listStatusFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, line := range strings.SplitAfter(string(list), "\n") {
+		if line != "" && !strings.HasPrefix(line, statusUntracked+"\t") {
+			buf.WriteString(line)
+		}
+	}
+	return buf.Bytes(), nil
+}
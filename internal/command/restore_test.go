@@ -0,0 +1,285 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRestoreSubcommand(t *testing.T) {
+	assert.NotNil(t, NewRestoreSubcommand())
+}
+
+func TestNewRestoreCommand(t *testing.T) {
+	previewCommand := `cd "$(git rev-parse --show-toplevel)" && git diff --color --cached -- {2} && git diff --color -- {2}`
+
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *restoreCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &restoreCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-w,alt-u,alt-s", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "main",
+			want: &restoreCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-w,alt-u,alt-s --query main", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newRestoreCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestRestoreCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	status := "M  staged.go\x00 M modified.go\x00?? untracked.go\x00"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		ioIn            string
+		backup          string
+		wantErr         error
+		wantGitCommands []string
+		wantIO          string
+		wantIOErr       string
+	}{
+		{
+			name: "select files",
+			fzfOutputs: []string{
+				"\nM \tstaged.go\n M\tmodified.go\n",
+			},
+			wantErr: nil,
+			wantIO:  "staged.go\nmodified.go\n",
+		},
+		{
+			name: "restore the working tree with a backup",
+			fzfOutputs: []string{
+				"alt-w\n M\tmodified.go\n",
+				"\n M\tmodified.go\n",
+			},
+			ioIn:    "y\n",
+			backup:  "abc\n",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"stash store --message git fzf restore backup abc",
+				"-C /repo restore --worktree -- modified.go",
+			},
+			wantIO:    "modified.go\n",
+			wantIOErr: "Discard changes of the working tree for modified.go? (y/N): The changes before restore are saved as stash@{0} (abc)\n",
+		},
+		{
+			name: "restore is canceled",
+			fzfOutputs: []string{
+				"alt-w\n M\tmodified.go\n",
+				"\n M\tmodified.go\n",
+			},
+			ioIn:      "n\n",
+			wantErr:   nil,
+			wantIO:    "modified.go\n",
+			wantIOErr: "Discard changes of the working tree for modified.go? (y/N): ",
+		},
+		{
+			name: "unstage",
+			fzfOutputs: []string{
+				"alt-u\nM \tstaged.go\n",
+				"\nM \tstaged.go\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C /repo restore --staged -- staged.go",
+			},
+			wantIO: "staged.go\n",
+		},
+		{
+			name: "restore from a source commit",
+			fzfOutputs: []string{
+				"alt-s\nM \tstaged.go\n M\tmodified.go\n",
+				"abc commit message\n",
+				"\nM \tstaged.go\n",
+			},
+			ioIn:    "yes\n",
+			backup:  "",
+			wantErr: nil,
+			wantGitCommands: []string{
+				"-C /repo restore --source=abc --staged --worktree -- staged.go modified.go",
+			},
+			wantIO:    "staged.go\n",
+			wantIOErr: "Restore the index and the working tree from abc for staged.go, modified.go? (y/N): ",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := restoreCli{
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "status --porcelain -z --untracked-files=all":
+					return []byte(status), nil
+				case "stash create git fzf restore backup":
+					return []byte(tc.backup), nil
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				if strings.HasPrefix(commandLine, "fzf ") {
+					assert.Equal(t, "fzf "+fzfOption, commandLine)
+					var list bytes.Buffer
+					_, err := list.ReadFrom(ioIn)
+					require.NoError(t, err)
+					assert.Equal(t, "M \tstaged.go\n M\tmodified.go\n", list.String())
+				}
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestRestoreInSubdirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// The path of the top directory may be different from dir by symbolic links
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	git("init", "-q")
+	// The changes are backed up by git stash create, which needs an identity
+	git("config", "user.name", "test")
+	git("config", "user.email", "test@example.com")
+	write("sub/modified.go", "a\n")
+	write("sub/staged.go", "a\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	write("sub/modified.go", "b\n")
+	write("sub/staged.go", "b\n")
+	git("add", "sub/staged.go")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	require.NoError(t, os.Chdir(filepath.Join(dir, "sub")))
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "git", args...).Output()
+	}
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		return exec.CommandContext(ctx, "git", args...).Run()
+	}
+	fzfOutputs := []string{
+		"alt-w\n M\tsub/modified.go\n",
+		"alt-u\nM \tsub/staged.go\n",
+		"",
+	}
+	fzfCount := 0
+	runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+		require.True(t, fzfCount < len(fzfOutputs))
+		out := fzfOutputs[fzfCount]
+		fzfCount++
+		return []byte(out), nil
+	}
+
+	sut := restoreCli{fzfOption: "--inline-info"}
+	require.NoError(t, sut.Run(context.Background(), strings.NewReader("y\n"), ioutil.Discard, ioutil.Discard))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "sub/modified.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(content))
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--untracked-files=no").Output()
+	require.NoError(t, err)
+	assert.Equal(t, " M sub/staged.go\n", string(status))
+}
@@ -0,0 +1,227 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type sparseCli struct {
+	fzfOption string
+}
+
+const (
+	sparseKeyAdd = "alt-a"
+
+	// All files under the directory are checked out
+	sparseStateIncluded = "included"
+	// Only files directly under the directory are checked out
+	sparseStateParent = "parent"
+	// No file under the directory is checked out
+	sparseStateExcluded = "excluded"
+)
+
+func NewSparseSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "sparse",
+		Short: "git sparse-checkout for directories selected with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			previewPath, err := flags.GetString("preview")
+			if err != nil {
+				return err
+			}
+			if previewPath != "" {
+				return previewSparseDirectory(context.Background(), os.Stdout, previewPath)
+			}
+
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			cli, err := newSparseCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.String("preview", "", "Only print the size and the number of files of the directory in HEAD")
+	_ = flags.MarkHidden("preview")
+	return command
+}
+
+func newSparseCli(fzfQuery string) (*sparseCli, error) {
+	self, err := selfCommand()
+	if err != nil {
+		return nil, err
+	}
+	previewCommand := shellQuoteInFzfOption(self) + " sparse --preview {2}"
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --nth 2 --expect %s", fzfOption, sparseKeyAdd)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &sparseCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c sparseCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	list, err := listSparseDirectories(ctx)
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("fzf %s", c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	key, lines := splitFzfExpectResult(out)
+	directories := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if fields := strings.SplitN(line, "\t", 2); len(fields) == 2 {
+			directories = append(directories, fields[1])
+		}
+	}
+	if len(directories) == 0 {
+		return nil
+	}
+	// Directories of git sparse-checkout are relative to the current directory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+
+	if key == sparseKeyAdd {
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "sparse-checkout", "add", "--"}, directories...)...); err != nil {
+			return fmt.Errorf("failed to add directories to the sparse checkout: %w", err)
+		}
+		return nil
+	}
+
+	// Directories which aren't selected are removed from the working tree
+	ok, err := confirm(ioIn, ioErr, fmt.Sprintf("Check out only %s?", strings.Join(directories, ", ")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append([]string{"-C", top, "sparse-checkout", "set", "--cone", "--"}, directories...)...); err != nil {
+		return fmt.Errorf("failed to set the sparse checkout: %w", err)
+	}
+	return nil
+}
+
+// listSparseDirectories lists directories in HEAD like <state><tab><path>, where paths are relative to the top directory
+func listSparseDirectories(ctx context.Context) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, "ls-tree", "--full-tree", "-d", "-r", "--name-only", "-z", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directories: %w", err)
+	}
+	cone, err := sparseCone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var list bytes.Buffer
+	for _, directory := range strings.Split(string(out), "\x00") {
+		if directory == "" {
+			continue
+		}
+		fmt.Fprintf(&list, "%s\t%s\n", sparseDirectoryState(cone, directory), directory)
+	}
+	return list.Bytes(), nil
+}
+
+// sparseCone returns directories of the cone, or nil if sparse checkout isn't enabled
+func sparseCone(ctx context.Context) ([]string, error) {
+	out, err := runGitCommandOutput(ctx, "config", "--bool", "--default", "false", "core.sparseCheckout")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get core.sparseCheckout: %w", err)
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		return nil, nil
+	}
+
+	out, err = runGitCommandOutput(ctx, "sparse-checkout", "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directories of the sparse checkout: %w", err)
+	}
+	cone := []string{}
+	for _, directory := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if directory != "" {
+			cone = append(cone, directory)
+		}
+	}
+	return cone, nil
+}
+
+func sparseDirectoryState(cone []string, directory string) string {
+	if cone == nil {
+		return sparseStateIncluded
+	}
+	state := sparseStateExcluded
+	for _, c := range cone {
+		if directory == c || strings.HasPrefix(directory, c+"/") {
+			return sparseStateIncluded
+		}
+		if strings.HasPrefix(c, directory+"/") {
+			state = sparseStateParent
+		}
+	}
+	return state
+}
+
+// previewSparseDirectory prints the size and the number of files under a directory in HEAD,
+// which are available even if the directory isn't checked out
+func previewSparseDirectory(ctx context.Context, ioOut io.Writer, directory string) error {
+	out, err := runGitCommandOutput(ctx, "ls-tree", "--full-tree", "-r", "-l", "-z", "HEAD", "--", directory)
+	if err != nil {
+		return fmt.Errorf("failed to list files of %s: %w", directory, err)
+	}
+	var size int64
+	files := 0
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// Each entry is like <mode> <type> <object> <size><tab><path>
+		fields := strings.Fields(strings.SplitN(entry, "\t", 2)[0])
+		if len(fields) != 4 {
+			continue
+		}
+		files++
+		// The size of a submodule is -
+		if s, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			size += s
+		}
+	}
+
+	out, err = runGitCommandOutput(ctx, "ls-tree", "--full-tree", "--name-only", "HEAD", "--", directory+"/")
+	if err != nil {
+		return fmt.Errorf("failed to list entries of %s: %w", directory, err)
+	}
+	if _, err := fmt.Fprintf(ioOut, "Size: %s\nFiles: %d\n\n%s", formatFileSize(size), files, out); err != nil {
+		return fmt.Errorf("failed to output the preview: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSparseSubcommand(t *testing.T) {
+	assert.NotNil(t, NewSparseSubcommand())
+}
+
+func TestNewSparseCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *sparseCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &sparseCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --nth 2 --expect alt-a", "'\\''git-fzf'\\'' sparse --preview {2}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "src",
+			want: &sparseCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --nth 2 --expect alt-a --query src", "'\\''git-fzf'\\'' sparse --preview {2}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newSparseCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestSparseCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name            string
+		fzfOutput       string
+		fzfErr          error
+		ioIn            string
+		wantErr         error
+		wantGitCommands []string
+		wantIOErr       string
+	}{
+		{
+			name:      "set the cone",
+			fzfOutput: "\nincluded\ta/b\nexcluded\te\n",
+			ioIn:      "y\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"-C /repo sparse-checkout set --cone -- a/b e",
+			},
+			wantIOErr: "Check out only a/b, e? (y/N): ",
+		},
+		{
+			name:      "not confirmed",
+			fzfOutput: "\nexcluded\te\n",
+			ioIn:      "\n",
+			wantErr:   nil,
+			wantIOErr: "Check out only e? (y/N): ",
+		},
+		{
+			name:      "add directories",
+			fzfOutput: "alt-a\nexcluded\te\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"-C /repo sparse-checkout add -- e",
+			},
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := sparseCli{
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "ls-tree --full-tree -d -r --name-only -z HEAD":
+					return []byte("a\x00a/b\x00c\x00c/d\x00e\x00"), nil
+				case "config --bool --default false core.sparseCheckout":
+					return []byte("true\n"), nil
+				case "sparse-checkout list":
+					return []byte("a/b\nc\n"), nil
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				list, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, "parent\ta\nincluded\ta/b\nincluded\tc\nincluded\tc/d\nexcluded\te\n", string(list))
+				return []byte(tc.fzfOutput), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, "", gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
+
+func TestListSparseDirectories_NotSparse(t *testing.T) {
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "ls-tree --full-tree -d -r --name-only -z HEAD":
+			return []byte("a\x00a/b\x00"), nil
+		case "config --bool --default false core.sparseCheckout":
+			return []byte("false\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	}
+	got, err := listSparseDirectories(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "included\ta\nincluded\ta/b\n", string(got))
+}
+
+func TestPreviewSparseDirectory(t *testing.T) {
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "ls-tree --full-tree -r -l -z HEAD -- src":
+			return []byte("100644 blob abc    1000\tsrc/a.go\x00100644 blob def     536\tsrc/lib/b.go\x00160000 commit ghi       -\tsrc/vendor\x00"), nil
+		case "ls-tree --full-tree --name-only HEAD -- src/":
+			return []byte("src/a.go\nsrc/lib\nsrc/vendor\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %v", args)
+	}
+	var gotIOOut bytes.Buffer
+	assert.NoError(t, previewSparseDirectory(context.Background(), &gotIOOut, "src"))
+	assert.Equal(t, "Size: 1.5K\nFiles: 3\n\nsrc/a.go\nsrc/lib\nsrc/vendor\n", gotIOOut.String())
+}
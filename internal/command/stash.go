@@ -1,27 +1,41 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 type stashCli struct {
-	listOptions []string
-	fzfOption   string
+	listOptions   []string
+	fzfOption     string
+	fileFzfOption string
 }
 
 const (
 	stashFzfPreviewCommand = "git stash show --color -p '{{.stash}}'"
+	// Untracked files are in the third parent of a stash. Paths of files are relative to the top directory.
+	stashFileFzfPreviewCommand = `cd "$(git rev-parse --show-toplevel)" && if [ {{.status}} = "?" ]; then git show --color {{.stash}}^3:{{.path}}; else git diff --color {{.stash}}^ {{.stash}} -- {{.path}}; fi`
+
+	stashFileStatusUntracked = "?"
+
+	stashKeyApply  = "alt-a"
+	stashKeyPop    = "alt-p"
+	stashKeyDrop   = "alt-d"
+	stashKeyBranch = "alt-b"
+	stashKeyShow   = "alt-s"
+	stashKeyFiles  = "alt-f"
+
+	stashFileKeyIndex = "alt-i"
 )
 
 func NewStashSubcommand() *cobra.Command {
-	return &cobra.Command{
+	command := &cobra.Command{
 		Use:   "stash [-- <git options>]",
 		Short: "git stash list with fzf",
 		Args:  cobra.MaximumNArgs(100),
@@ -42,6 +56,8 @@ func NewStashSubcommand() *cobra.Command {
 			return nil
 		},
 	}
+	command.AddCommand(NewStashPushSubcommand())
+	return command
 }
 
 func newStashCli(gitOptions []string, fzfQuery string) (*stashCli, error) {
@@ -56,31 +72,210 @@ func newStashCli(gitOptions []string, fzfQuery string) (*stashCli, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get fzf option: %w", err)
 	}
+	fzfOption = fmt.Sprintf("%s --expect %s,%s,%s,%s,%s,%s", fzfOption, stashKeyApply, stashKeyPop, stashKeyDrop, stashKeyBranch, stashKeyShow, stashKeyFiles)
 	if fzfQuery != "" {
 		fzfOption = fzfOption + " --query " + fzfQuery
 	}
 
+	filePreviewCommand, err := commandFromTemplate("preview", stashFileFzfPreviewCommand, map[string]interface{}{
+		"stash":  "{1}",
+		"status": "{2}",
+		"path":   "{3}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	fileFzfOption, err := getFzfOption(filePreviewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fileFzfOption = fmt.Sprintf("%s --delimiter '\\t' --with-nth 2.. --expect %s", fileFzfOption, stashFileKeyIndex)
+
 	return &stashCli{
-		listOptions: gitOptions,
-		fzfOption:   fzfOption,
+		listOptions:   gitOptions,
+		fzfOption:     fzfOption,
+		fileFzfOption: fileFzfOption,
 	}, nil
 }
 
 func (c stashCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
 	command := fmt.Sprintf("git stash list --format='%%gd %%gs' %s | fzf %s", strings.Join(c.listOptions, " "), c.fzfOption)
-	out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// Script canceled by Ctrl-c
-			// Only for bash?: http://tldp.org/LDP/abs/html/exitcodes.html
-			if exitErr.ExitCode() == 130 {
+	// The list is reloaded after each action
+	for {
+		out, err := runCommandWithFzf(ctx, command, ioIn, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
 				return nil
 			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		if len(lines) == 0 {
+			return nil
+		}
+		stashes := make([]string, len(lines))
+		for i, line := range lines {
+			stashes[i] = strings.Fields(line)[0]
+		}
+
+		// Actions except drop are run only for the first stash
+		switch key {
+		case stashKeyApply, stashKeyPop:
+			action := "apply"
+			if key == stashKeyPop {
+				action = "pop"
+			}
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "stash", action, stashes[0]); err != nil {
+				return fmt.Errorf("failed to %s %s: %w", action, stashes[0], err)
+			}
+		case stashKeyDrop:
+			if err := dropStashes(ctx, ioIn, ioErr, stashes); err != nil {
+				return err
+			}
+		case stashKeyBranch:
+			branch, err := promptInput(ioIn, ioErr, fmt.Sprintf("Branch name for %s", stashes[0]), "")
+			if err != nil {
+				return err
+			}
+			if branch == "" {
+				continue
+			}
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "stash", "branch", branch, stashes[0]); err != nil {
+				return fmt.Errorf("failed to create a branch from %s: %w", stashes[0], err)
+			}
+		case stashKeyShow:
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "--paginate", "stash", "show", "-p", stashes[0]); err != nil {
+				return fmt.Errorf("failed to show %s: %w", stashes[0], err)
+			}
+		case stashKeyFiles:
+			if err := c.restoreStashFiles(ctx, ioIn, ioErr, stashes[0]); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(stashes, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// dropStashes drops stashes by resolving them into commits first,
+// because the indexes like stash@{1} are shifted once a stash is dropped
+func dropStashes(ctx context.Context, ioIn io.Reader, ioErr io.Writer, stashes []string) error {
+	out, err := runGitCommandOutput(ctx, append([]string{"rev-parse"}, stashes...)...)
+	if err != nil {
+		return fmt.Errorf("failed to resolve stashes: %w", err)
+	}
+	for _, commit := range strings.Fields(string(out)) {
+		list, err := runGitCommandOutput(ctx, "stash", "list", "--format=%H")
+		if err != nil {
+			return fmt.Errorf("failed to list stashes: %w", err)
+		}
+		index := -1
+		for i, c := range strings.Fields(string(list)) {
+			if c == commit {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			continue
+		}
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "stash", "drop", fmt.Sprintf("stash@{%d}", index)); err != nil {
+			return fmt.Errorf("failed to drop stash@{%d}: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// restoreStashFiles restores files selected from a stash into the working tree, or the index by a key
+func (c stashCli) restoreStashFiles(ctx context.Context, ioIn io.Reader, ioErr io.Writer, stash string) error {
+	list, err := listStashFiles(ctx, stash)
+	if err != nil {
+		return err
+	}
+	command := fmt.Sprintf("fzf %s", c.fileFzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+	if err != nil {
+		// Go back to the list of stashes
+		if isCanceledByUser(err) {
+			return nil
 		}
 		return fmt.Errorf("failed to run the command %s: %w", command, err)
 	}
-	if err := writeFzfResult(ioOut, out, 0); err != nil {
+	key, lines := splitFzfExpectResult(out)
+
+	var trackedPaths []string
+	var untrackedPaths []string
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == stashFileStatusUntracked {
+			untrackedPaths = append(untrackedPaths, fields[2])
+		} else {
+			trackedPaths = append(trackedPaths, fields[2])
+		}
+	}
+
+	// The second parent of a stash is the index when it was created, and the third one has untracked files
+	target := "--worktree"
+	trackedSource := stash
+	if key == stashFileKeyIndex {
+		target = "--staged"
+		trackedSource = stash + "^2"
+	}
+	// Paths of files are relative to the top directory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
 		return err
 	}
+	for _, restore := range []struct {
+		source string
+		paths  []string
+	}{
+		{source: trackedSource, paths: trackedPaths},
+		{source: stash + "^3", paths: untrackedPaths},
+	} {
+		if len(restore.paths) == 0 {
+			continue
+		}
+		args := append([]string{"-C", top, "restore", "--source=" + restore.source, target, "--"}, restore.paths...)
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+			return fmt.Errorf("failed to restore files from %s: %w", restore.source, err)
+		}
+	}
 	return nil
 }
+
+// listStashFiles lists files changed in a stash with their status, including untracked files if they were stashed.
+// Paths are relative to the top directory.
+func listStashFiles(ctx context.Context, stash string) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, "stash", "show", "--name-status", "--no-renames", stash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", stash, err)
+	}
+	var list bytes.Buffer
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			fmt.Fprintf(&list, "%s\t%s\n", stash, line)
+		}
+	}
+
+	// The third parent exists only if a stash was created with --include-untracked
+	if _, err := runGitCommandOutput(ctx, "rev-parse", "--verify", "--quiet", stash+"^3"); err != nil {
+		return list.Bytes(), nil
+	}
+	untracked, err := runGitCommandOutput(ctx, "ls-tree", "-r", "--name-only", "--full-tree", stash+"^3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files of %s: %w", stash, err)
+	}
+	for _, path := range strings.Split(strings.TrimSpace(string(untracked)), "\n") {
+		if path != "" {
+			fmt.Fprintf(&list, "%s\t%s\t%s\n", stash, stashFileStatusUntracked, path)
+		}
+	}
+	return list.Bytes(), nil
+}
@@ -0,0 +1,184 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type stashPushCli struct {
+	keepIndex        bool
+	includeUntracked bool
+	message          string
+	fzfOption        string
+}
+
+const (
+	// Untracked files don't have any diff. Paths of git status are relative to the top directory.
+	stashPushFzfPreviewCommand = `cd "$(git rev-parse --show-toplevel)" && if [ {{.status}} = "??" ]; then cat {{.path}}; else git diff --color HEAD -- {{.path}}; fi`
+
+	statusUntracked = "??"
+)
+
+func NewStashPushSubcommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "push",
+		Short: "git stash push for files selected with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+			keepIndex, err := flags.GetBool("keep-index")
+			if err != nil {
+				return err
+			}
+			includeUntracked, err := flags.GetBool("include-untracked")
+			if err != nil {
+				return err
+			}
+			message, err := flags.GetString("message")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newStashPushCli(keepIndex, includeUntracked, message, fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	flags := command.Flags()
+	flags.BoolP("keep-index", "k", false, "Keep changes already added to the index")
+	flags.BoolP("include-untracked", "u", false, "Stash also untracked files. Selected untracked files are stashed even without this option")
+	flags.StringP("message", "m", "", "The message of the stash. It's asked if it's not given")
+	return command
+}
+
+func newStashPushCli(keepIndex bool, includeUntracked bool, message string, fzfQuery string) (*stashPushCli, error) {
+	previewCommand, err := commandFromTemplate("preview", stashPushFzfPreviewCommand, map[string]interface{}{
+		"status": "{1}",
+		"path":   "{2}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fzfOption + " --delimiter '\\t'"
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &stashPushCli{
+		keepIndex:        keepIndex,
+		includeUntracked: includeUntracked,
+		message:          message,
+		fzfOption:        fzfOption,
+	}, nil
+}
+
+func (c stashPushCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	list, err := listStatusFiles(ctx)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		_, err := fmt.Fprintln(ioErr, "There is no change to stash")
+		return err
+	}
+
+	command := fmt.Sprintf("fzf %s", c.fzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(list), ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+
+	includeUntracked := c.includeUntracked
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		// git stash push fails for untracked files without --include-untracked
+		if fields[0] == statusUntracked {
+			includeUntracked = true
+		}
+		paths = append(paths, fields[1])
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	message := c.message
+	if message == "" {
+		message, err = promptInput(ioIn, ioErr, "Message of the stash", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Paths of git status are relative to the top directory
+	top, err := gitTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+	args := []string{"-C", top, "stash", "push"}
+	if c.keepIndex {
+		args = append(args, "--keep-index")
+	}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if message != "" {
+		args = append(args, "--message", message)
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+		return fmt.Errorf("failed to push a stash: %w", err)
+	}
+	return nil
+}
+
+// listStatusFiles lists staged, modified and untracked files like XY<tab><path> from git status --porcelain -z,
+// where paths are relative to the top directory
+func listStatusFiles(ctx context.Context) ([]byte, error) {
+	out, err := runGitCommandOutput(ctx, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the status: %w", err)
+	}
+	var list bytes.Buffer
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status := entry[:2]
+		fmt.Fprintf(&list, "%s\t%s\n", status, entry[3:])
+		// The original path of a renamed or copied file follows the entry
+		if status[0] == 'R' || status[0] == 'C' {
+			i++
+		}
+	}
+	return list.Bytes(), nil
+}
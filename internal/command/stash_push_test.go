@@ -0,0 +1,212 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStashPushSubcommand(t *testing.T) {
+	assert.NotNil(t, NewStashPushSubcommand())
+}
+
+func TestNewStashPushCommand(t *testing.T) {
+	previewCommand := `cd "$(git rev-parse --show-toplevel)" && if [ {1} = "??" ]; then cat {2}; else git diff --color HEAD -- {2}; fi`
+
+	testCases := []struct {
+		name             string
+		keepIndex        bool
+		includeUntracked bool
+		message          string
+		fzfQuery         string
+		envVars          map[string]string
+		want             *stashPushCli
+		wantErr          error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &stashPushCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t'", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:             "all options",
+			keepIndex:        true,
+			includeUntracked: true,
+			message:          "wip",
+			fzfQuery:         "main",
+			want: &stashPushCli{
+				keepIndex:        true,
+				includeUntracked: true,
+				message:          "wip",
+				fzfOption:        fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --query main", previewCommand, defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newStashPushCli(tc.keepIndex, tc.includeUntracked, tc.message, tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestStashPushCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	status := "M  staged.go\x00 M modified.go\x00R  new.go\x00old.go\x00?? my file.txt\x00"
+	wantList := "M \tstaged.go\n M\tmodified.go\nR \tnew.go\n??\tmy file.txt\n"
+
+	testCases := []struct {
+		name            string
+		sut             stashPushCli
+		status          string
+		fzfOutput       string
+		fzfErr          error
+		ioIn            string
+		wantErr         error
+		wantGitCommands []string
+		wantIOErr       string
+	}{
+		{
+			name: "prompted message",
+			sut: stashPushCli{
+				fzfOption: fzfOption,
+			},
+			status:    status,
+			fzfOutput: "M \tstaged.go\n M\tmodified.go\n",
+			ioIn:      "wip\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"-C /repo stash push --message wip -- staged.go modified.go",
+			},
+			wantIOErr: "Message of the stash: ",
+		},
+		{
+			name: "all options",
+			sut: stashPushCli{
+				keepIndex:        true,
+				includeUntracked: true,
+				message:          "wip",
+				fzfOption:        fzfOption,
+			},
+			status:    status,
+			fzfOutput: " M\tmodified.go\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"-C /repo stash push --keep-index --include-untracked --message wip -- modified.go",
+			},
+		},
+		{
+			name: "untracked file without message",
+			sut: stashPushCli{
+				fzfOption: fzfOption,
+			},
+			status:    status,
+			fzfOutput: "??\tmy file.txt\n",
+			ioIn:      "\n",
+			wantErr:   nil,
+			wantGitCommands: []string{
+				"-C /repo stash push --include-untracked -- my file.txt",
+			},
+			wantIOErr: "Message of the stash: ",
+		},
+		{
+			name: "no change",
+			sut: stashPushCli{
+				fzfOption: fzfOption,
+			},
+			status:    "",
+			wantErr:   nil,
+			wantIOErr: "There is no change to stash\n",
+		},
+		{
+			name: "command with fzf error",
+			sut: stashPushCli{
+				fzfOption: fzfOption,
+			},
+			status:  status,
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name: "command with fzf exit error (not 130)",
+			sut: stashPushCli{
+				fzfOption: fzfOption,
+			},
+			status:  status,
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				if strings.Join(args, " ") == "rev-parse --show-toplevel" {
+					return []byte("/repo\n"), nil
+				}
+				assert.Equal(t, "status --porcelain -z --untracked-files=all", strings.Join(args, " "))
+				return []byte(tc.status), nil
+			}
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				list, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, wantList, string(list))
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				return []byte(tc.fzfOutput), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, "", gotIOOut.String())
+			assert.Equal(t, tc.wantIOErr, gotIOErr.String())
+		})
+	}
+}
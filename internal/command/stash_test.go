@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -20,6 +22,11 @@ func TestNewStashSubcommand(t *testing.T) {
 }
 
 func TestNewStashCommand(t *testing.T) {
+	fileFzfOption := fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --with-nth 2.. --expect alt-i",
+		`cd "$(git rev-parse --show-toplevel)" && if [ {2} = "?" ]; then git show --color {1}^3:{3}; else git diff --color {1}^ {1} -- {3}; fi`,
+		defaultFzfBindOption,
+	)
+
 	testCases := []struct {
 		name       string
 		gitOptions []string
@@ -33,8 +40,9 @@ func TestNewStashCommand(t *testing.T) {
 			gitOptions: []string{},
 			fzfQuery:   "",
 			want: &stashCli{
-				listOptions: []string{},
-				fzfOption:   fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s", "git stash show --color -p '{1}'", defaultFzfBindOption),
+				listOptions:   []string{},
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --expect alt-a,alt-p,alt-d,alt-b,alt-s,alt-f", "git stash show --color -p '{1}'", defaultFzfBindOption),
+				fileFzfOption: fileFzfOption,
 			},
 			wantErr: nil,
 		},
@@ -50,7 +58,8 @@ func TestNewStashCommand(t *testing.T) {
 					"--diff-filter",
 					"A",
 				},
-				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --query config", "git stash show --color -p '{1}'", defaultFzfBindOption),
+				fzfOption:     fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --expect alt-a,alt-p,alt-d,alt-b,alt-s,alt-f --query config", "git stash show --color -p '{1}'", defaultFzfBindOption),
+				fileFzfOption: fileFzfOption,
 			},
 			wantErr: nil,
 		},
@@ -92,7 +101,7 @@ func TestStashCli_Run(t *testing.T) {
 			"git stash list --format='%gd %gs' --diff-filter A",
 			fzfOption,
 		), commandLine)
-		return bytes.NewBufferString("stash@{0} WIP on branch: abc Commit message1\nstash@{1} autostash\n").Bytes(), nil
+		return bytes.NewBufferString("\nstash@{0} WIP on branch: abc Commit message1\nstash@{1} autostash\n").Bytes(), nil
 	}
 	defaultWantErr := errors.New("want error")
 	exitErr := exec.ExitError{}
@@ -160,3 +169,245 @@ func TestStashCli_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestStashCli_Run_Actions(t *testing.T) {
+	fzfOption := "--inline-info"
+	stashCommits := "aaa\nbbb\nccc\n"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		ioIn            string
+		wantGitCommands []string
+		wantIO          string
+	}{
+		{
+			name: "apply and pop only the first stash",
+			fzfOutputs: []string{
+				"alt-a\nstash@{1} autostash\nstash@{2} autostash\n",
+				"alt-p\nstash@{0} WIP on branch: abc Commit message1\n",
+			},
+			wantGitCommands: []string{
+				"stash apply stash@{1}",
+				"stash pop stash@{0}",
+			},
+		},
+		{
+			name: "drop multiple stashes by commits",
+			fzfOutputs: []string{
+				"alt-d\nstash@{0} WIP on branch: abc Commit message1\nstash@{2} autostash\n",
+			},
+			wantGitCommands: []string{
+				"stash drop stash@{0}",
+				// ccc was stash@{2} before stash@{0} was dropped
+				"stash drop stash@{1}",
+			},
+		},
+		{
+			name: "drop stashes selected from the bottom",
+			fzfOutputs: []string{
+				"alt-d\nstash@{2} autostash\nstash@{0} WIP on branch: abc Commit message1\n",
+			},
+			wantGitCommands: []string{
+				"stash drop stash@{2}",
+				// aaa is still stash@{0} after stash@{2} was dropped
+				"stash drop stash@{0}",
+			},
+		},
+		{
+			name: "branch",
+			fzfOutputs: []string{
+				"alt-b\nstash@{1} autostash\n",
+				"alt-b\nstash@{1} autostash\n",
+			},
+			ioIn: "feature\n\n",
+			wantGitCommands: []string{
+				"stash branch feature stash@{1}",
+			},
+		},
+		{
+			name: "show and then select",
+			fzfOutputs: []string{
+				"alt-s\nstash@{1} autostash\n",
+				"\nstash@{1} autostash\n",
+			},
+			wantGitCommands: []string{
+				"--paginate stash show -p stash@{1}",
+			},
+			wantIO: "stash@{1}\n",
+		},
+		{
+			name: "restore files into the working tree and the index",
+			fzfOutputs: []string{
+				"alt-f\nstash@{1} autostash\n",
+				"\nstash@{1}\tM\tmain.go\nstash@{1}\t?\tnew.go\n",
+				"alt-f\nstash@{1} autostash\n",
+				"alt-i\nstash@{1}\tA\tREADME.md\n",
+			},
+			wantGitCommands: []string{
+				"-C /repo restore --source=stash@{1} --worktree -- main.go",
+				"-C /repo restore --source=stash@{1}^3 --worktree -- new.go",
+				"-C /repo restore --source=stash@{1}^2 --staged -- README.md",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := stashCli{
+				listOptions: []string{},
+				fzfOption:   fzfOption,
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				if fzfCount >= len(tc.fzfOutputs) {
+					return nil, exec.Command("sh", "-c", "exit 130").Run()
+				}
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			commits := stashCommits
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				switch strings.Join(args, " ") {
+				case "rev-parse stash@{0} stash@{2}":
+					return []byte("aaa\nccc\n"), nil
+				case "rev-parse stash@{2} stash@{0}":
+					return []byte("ccc\naaa\n"), nil
+				case "stash list --format=%H":
+					return []byte(commits), nil
+				case "stash show --name-status --no-renames stash@{1}":
+					return []byte("M\tmain.go\nA\tREADME.md\n"), nil
+				case "rev-parse --verify --quiet stash@{1}^3":
+					return []byte("ddd\n"), nil
+				case "ls-tree -r --name-only --full-tree stash@{1}^3":
+					return []byte("new.go\n"), nil
+				case "rev-parse --show-toplevel":
+					return []byte("/repo\n"), nil
+				}
+				return nil, fmt.Errorf("unexpected command: %v", args)
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				if args[1] == "drop" {
+					// Remove the dropped stash, which is stash@{<index>}
+					var index int
+					_, err := fmt.Sscanf(args[2], "stash@{%d}", &index)
+					require.NoError(t, err)
+					lines := strings.SplitAfter(commits, "\n")
+					require.True(t, index < len(lines)-1, args[2])
+					commits = strings.Join(append(lines[:index], lines[index+1:]...), "")
+				}
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
+
+func TestListStashFiles(t *testing.T) {
+	testCases := []struct {
+		name       string
+		gitOutputs map[string]string
+		want       string
+	}{
+		{
+			name: "with untracked files",
+			gitOutputs: map[string]string{
+				"stash show --name-status --no-renames stash@{0}": "M\tmain.go\nD\told.go\n",
+				"rev-parse --verify --quiet stash@{0}^3":          "ddd\n",
+				"ls-tree -r --name-only --full-tree stash@{0}^3":  "new.go\ndir/new.txt\n",
+			},
+			want: "stash@{0}\tM\tmain.go\nstash@{0}\tD\told.go\nstash@{0}\t?\tnew.go\nstash@{0}\t?\tdir/new.txt\n",
+		},
+		{
+			name: "without untracked files",
+			gitOutputs: map[string]string{
+				"stash show --name-status --no-renames stash@{0}": "M\tmain.go\n",
+			},
+			want: "stash@{0}\tM\tmain.go\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				out, ok := tc.gitOutputs[strings.Join(args, " ")]
+				if !ok {
+					return nil, errors.New("error")
+				}
+				return []byte(out), nil
+			}
+			got, gotErr := listStashFiles(context.Background(), "stash@{0}")
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestStashFilesInSubdirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-fzf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// The path of the top directory may be different from dir by symbolic links
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	git("init", "-q")
+	write("main.go", "a\n")
+	write("sub/main.go", "a\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	write("sub/main.go", "b\n")
+	write("new.go", "c\n")
+	git("stash", "push", "-q", "--include-untracked")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	require.NoError(t, os.Chdir(filepath.Join(dir, "sub")))
+	runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "git", args...).Output()
+	}
+	runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+		return exec.CommandContext(ctx, "git", args...).Run()
+	}
+	var gotList string
+	runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+		list, err := ioutil.ReadAll(ioIn)
+		require.NoError(t, err)
+		gotList = string(list)
+		// All files are selected without a key
+		return append([]byte("\n"), list...), nil
+	}
+
+	sut := stashCli{fileFzfOption: "--inline-info"}
+	require.NoError(t, sut.restoreStashFiles(context.Background(), nil, ioutil.Discard, "stash@{0}"))
+	assert.Equal(t, "stash@{0}\tM\tsub/main.go\nstash@{0}\t?\tnew.go\n", gotList)
+	for path, want := range map[string]string{
+		"sub/main.go": "b\n",
+		"new.go":      "c\n",
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, path))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+}
@@ -0,0 +1,219 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type submoduleCli struct {
+	fzfOption string
+}
+
+type submodule struct {
+	path string
+	// recorded is the commit recorded in the superproject
+	recorded string
+	// current is the checked out commit, or empty if the submodule isn't initialized
+	current     string
+	initialized bool
+	conflicted  bool
+	dirty       bool
+}
+
+const (
+	// Commits of the current commit but not of the recorded one are shown with >, and the other commits with <
+	submoduleFzfPreviewCommand = "git -C {{.path}} log --oneline --color --left-right {{.recorded}}...{{.current}}"
+
+	submoduleKeyUpdate = "alt-u"
+	submoduleKeyInit   = "alt-i"
+	submoduleKeySync   = "alt-s"
+)
+
+func NewSubmoduleSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "submodule",
+		Short: "git submodule status with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newSubmoduleCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newSubmoduleCli(fzfQuery string) (*submoduleCli, error) {
+	previewCommand, err := commandFromTemplate("preview", submoduleFzfPreviewCommand, map[string]interface{}{
+		"path":     "{1}",
+		"recorded": "{2}",
+		"current":  "{3}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --expect %s,%s,%s", fzfOption, submoduleKeyUpdate, submoduleKeyInit, submoduleKeySync)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+
+	return &submoduleCli{
+		fzfOption: fzfOption,
+	}, nil
+}
+
+func (c submoduleCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// The list is reloaded after each action
+	for {
+		submodules, err := listSubmodules(ctx)
+		if err != nil {
+			return err
+		}
+		if len(submodules) == 0 {
+			_, err := fmt.Fprintln(ioErr, "There is no submodule")
+			return err
+		}
+		var list bytes.Buffer
+		for _, s := range submodules {
+			fmt.Fprintln(&list, s.String())
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		paths := make([]string, len(lines))
+		for i, line := range lines {
+			paths[i] = strings.SplitN(line, "\t", 2)[0]
+		}
+
+		var args []string
+		switch key {
+		case submoduleKeyUpdate:
+			args = []string{"submodule", "update", "--"}
+		case submoduleKeyInit:
+			args = []string{"submodule", "update", "--init", "--"}
+		case submoduleKeySync:
+			args = []string{"submodule", "sync", "--"}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(paths, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, append(args, paths...)...); err != nil {
+			return fmt.Errorf("failed to run git %s: %w", strings.Join(args[:len(args)-1], " "), err)
+		}
+	}
+}
+
+// listSubmodules lists submodules with both the recorded and the checked out commits.
+// git submodule status prints the checked out commit, and the recorded one with --cached.
+func listSubmodules(ctx context.Context) ([]submodule, error) {
+	out, err := runGitCommandOutput(ctx, "submodule", "status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the status of submodules: %w", err)
+	}
+	cachedOut, err := runGitCommandOutput(ctx, "submodule", "status", "--cached")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the recorded commits of submodules: %w", err)
+	}
+	recorded := map[string]string{}
+	for _, s := range parseSubmoduleStatus(cachedOut) {
+		recorded[s.path] = s.current
+	}
+
+	submodules := parseSubmoduleStatus(out)
+	for i, s := range submodules {
+		submodules[i].recorded = recorded[s.path]
+		if !s.initialized {
+			submodules[i].current = ""
+			continue
+		}
+		status, err := runGitCommandOutput(ctx, "-C", s.path, "status", "--porcelain")
+		if err == nil && len(bytes.TrimSpace(status)) > 0 {
+			submodules[i].dirty = true
+		}
+	}
+	return submodules, nil
+}
+
+// parseSubmoduleStatus parses lines of git submodule status like
+// +<commit> <path> (<describe>)
+// where the first character is - if not initialized, + if the commit is different from the recorded one, or U for conflicts
+func parseSubmoduleStatus(out []byte) []submodule {
+	var submodules []submodule
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		state := line[0]
+		fields := strings.SplitN(line[1:], " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		path := fields[1]
+		if i := strings.LastIndex(path, " ("); i >= 0 && strings.HasSuffix(path, ")") {
+			path = path[:i]
+		}
+		submodules = append(submodules, submodule{
+			path:        path,
+			current:     fields[0],
+			initialized: state != '-',
+			conflicted:  state == 'U',
+		})
+	}
+	return submodules
+}
+
+func (s submodule) state() string {
+	var states []string
+	switch {
+	case !s.initialized:
+		states = append(states, "not initialized")
+	case s.conflicted:
+		states = append(states, "conflicted")
+	case s.current != s.recorded:
+		states = append(states, "out of date")
+	}
+	if s.dirty {
+		states = append(states, "dirty")
+	}
+	return strings.Join(states, ",")
+}
+
+func (s submodule) String() string {
+	shortHash := func(hash string) string {
+		if len(hash) > shortHashLength {
+			return hash[:shortHashLength]
+		}
+		return hash
+	}
+	return strings.Join([]string{s.path, shortHash(s.recorded), shortHash(s.current), s.state()}, "\t")
+}
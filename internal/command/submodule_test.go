@@ -0,0 +1,184 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSubmoduleSubcommand(t *testing.T) {
+	assert.NotNil(t, NewSubmoduleSubcommand())
+}
+
+func TestNewSubmoduleCommand(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *submoduleCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &submoduleCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-u,alt-i,alt-s", "git -C {1} log --oneline --color --left-right {2}...{3}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "lib",
+			want: &submoduleCli{
+				fzfOption: fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-u,alt-i,alt-s --query lib", "git -C {1} log --oneline --color --left-right {2}...{3}", defaultFzfBindOption),
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newSubmoduleCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestSubmoduleCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+	gitOutputs := map[string]string{
+		"submodule status":             "+0a0394a0e041b7e711f39343b5fb1f94704b0fd4 my lib (heads/master)\n c837feb08b9fcea43b18ee0b75864fa966731bb7 other (heads/master)\n-1111111111111111111111111111111111111111 vendor\n",
+		"submodule status --cached":    "+c837feb08b9fcea43b18ee0b75864fa966731bb7 my lib (remotes/origin/HEAD)\n c837feb08b9fcea43b18ee0b75864fa966731bb7 other (heads/master)\n-1111111111111111111111111111111111111111 vendor\n",
+		"-C my lib status --porcelain": "",
+		"-C other status --porcelain":  "?? x\n",
+		"-C vendor status --porcelain": "",
+	}
+	wantList := "my lib\tc837feb\t0a0394a\tout of date\nother\tc837feb\tc837feb\tdirty\nvendor\t1111111\t\tnot initialized\n"
+
+	testCases := []struct {
+		name            string
+		fzfOutputs      []string
+		fzfErr          error
+		wantErr         error
+		wantGitCommands []string
+		wantIO          string
+	}{
+		{
+			name: "select submodules",
+			fzfOutputs: []string{
+				"\nmy lib\tc837feb\t0a0394a\tout of date\nother\tc837feb\tc837feb\tdirty\n",
+			},
+			wantErr: nil,
+			wantIO:  "my lib\nother\n",
+		},
+		{
+			name: "update, init and sync",
+			fzfOutputs: []string{
+				"alt-u\nmy lib\tc837feb\t0a0394a\tout of date\n",
+				"alt-i\nvendor\t1111111\t\tnot initialized\n",
+				"alt-s\nmy lib\tc837feb\t0a0394a\tout of date\nother\tc837feb\tc837feb\tdirty\n",
+				"\nvendor\t1111111\t\tnot initialized\n",
+			},
+			wantErr: nil,
+			wantGitCommands: []string{
+				"submodule update -- my lib",
+				"submodule update --init -- vendor",
+				"submodule sync -- my lib other",
+			},
+			wantIO: "vendor\n",
+		},
+		{
+			name:    "command with fzf error",
+			fzfErr:  defaultWantErr,
+			wantErr: defaultWantErr,
+		},
+		{
+			name:    "command with fzf exit error (not 130)",
+			fzfErr:  &exitErr,
+			wantErr: &exitErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := submoduleCli{
+				fzfOption: fzfOption,
+			}
+
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				out, ok := gitOutputs[strings.Join(args, " ")]
+				require.True(t, ok, args)
+				return []byte(out), nil
+			}
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				assert.Equal(t, "fzf "+fzfOption, commandLine)
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				list, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.Equal(t, wantList, string(list))
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := sut.Run(context.Background(), strings.NewReader("in"), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+		})
+	}
+}
+
+func TestParseSubmoduleStatus(t *testing.T) {
+	out := "+0a0394a my lib (heads/master)\n-1111111 vendor\nU0000000 conflicted\n"
+	want := []submodule{
+		{path: "my lib", current: "0a0394a", initialized: true},
+		{path: "vendor", current: "1111111", initialized: false},
+		{path: "conflicted", current: "0000000", initialized: true, conflicted: true},
+	}
+	assert.Equal(t, want, parseSubmoduleStatus([]byte(out)))
+	assert.Nil(t, parseSubmoduleStatus([]byte("")))
+}
@@ -0,0 +1,272 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type worktreeCli struct {
+	fzfOption       string
+	branchFzfOption string
+}
+
+type worktree struct {
+	path     string
+	head     string
+	branch   string
+	detached bool
+	bare     bool
+	locked   bool
+	prunable bool
+}
+
+const (
+	worktreeFzfPreviewCommand       = "git -C {{.path}} status --short --branch && git -C {{.path}} log --oneline --color -10"
+	worktreeBranchFzfPreviewCommand = "git log --oneline --color -10 {{.branch}}"
+
+	worktreeKeyAdd    = "alt-a"
+	worktreeKeyRemove = "alt-d"
+	worktreeKeyPrune  = "alt-p"
+)
+
+func NewWorktreeSubcommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "worktree",
+		Short: "git worktree list with fzf",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			fzfQuery, err := flags.GetString("query")
+			if err != nil {
+				return err
+			}
+
+			cli, err := newWorktreeCli(fzfQuery)
+			if err != nil {
+				return err
+			}
+			if err := cli.Run(context.Background(), os.Stdin, os.Stdout, os.Stderr); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newWorktreeCli(fzfQuery string) (*worktreeCli, error) {
+	previewCommand, err := commandFromTemplate("preview", worktreeFzfPreviewCommand, map[string]interface{}{
+		"path": "{1}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+	branchPreviewCommand, err := commandFromTemplate("preview", worktreeBranchFzfPreviewCommand, map[string]interface{}{
+		"branch": "{}",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid fzf preview command: %w", err)
+	}
+
+	fzfOption, err := getFzfOption(previewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	fzfOption = fmt.Sprintf("%s --delimiter '\\t' --expect %s,%s,%s", fzfOption, worktreeKeyAdd, worktreeKeyRemove, worktreeKeyPrune)
+	if fzfQuery != "" {
+		fzfOption = fzfOption + " --query " + fzfQuery
+	}
+	branchFzfOption, err := getFzfOption(branchPreviewCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fzf option: %w", err)
+	}
+	branchFzfOption = branchFzfOption + " --no-multi"
+
+	return &worktreeCli{
+		fzfOption:       fzfOption,
+		branchFzfOption: branchFzfOption,
+	}, nil
+}
+
+func (c worktreeCli) Run(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer) error {
+	// The list is reloaded after each action
+	for {
+		worktrees, err := listWorktrees(ctx)
+		if err != nil {
+			return err
+		}
+		var list bytes.Buffer
+		for _, w := range worktrees {
+			fmt.Fprintln(&list, w.String())
+		}
+
+		command := fmt.Sprintf("fzf %s", c.fzfOption)
+		out, err := runCommandWithFzf(ctx, command, &list, ioErr)
+		if err != nil {
+			if isCanceledByUser(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to run the command %s: %w", command, err)
+		}
+		key, lines := splitFzfExpectResult(out)
+		paths := make([]string, len(lines))
+		for i, line := range lines {
+			paths[i] = strings.SplitN(line, "\t", 2)[0]
+		}
+
+		switch key {
+		case worktreeKeyAdd:
+			if err := c.addWorktree(ctx, ioIn, ioErr, worktrees[0].path); err != nil {
+				return err
+			}
+		case worktreeKeyRemove:
+			if err := removeWorktrees(ctx, ioIn, ioErr, paths); err != nil {
+				return err
+			}
+		case worktreeKeyPrune:
+			if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "worktree", "prune", "--verbose"); err != nil {
+				return fmt.Errorf("failed to prune worktrees: %w", err)
+			}
+		default:
+			if _, err := io.WriteString(ioOut, strings.Join(paths, "\n")+"\n"); err != nil {
+				return fmt.Errorf("failed to output the result: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// addWorktree adds a worktree for a branch picked by fzf next to the main worktree
+func (c worktreeCli) addWorktree(ctx context.Context, ioIn io.Reader, ioErr io.Writer, mainWorktreePath string) error {
+	branches, err := runGitCommandOutput(ctx, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	command := fmt.Sprintf("fzf %s", c.branchFzfOption)
+	out, err := runCommandWithFzf(ctx, command, bytes.NewReader(branches), ioErr)
+	if err != nil {
+		if isCanceledByUser(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to run the command %s: %w", command, err)
+	}
+	branch := strings.TrimSpace(string(out))
+
+	defaultPath := filepath.Join(
+		filepath.Dir(mainWorktreePath),
+		filepath.Base(mainWorktreePath)+"-"+strings.ReplaceAll(branch, "/", "-"),
+	)
+	path, err := promptInput(ioIn, ioErr, fmt.Sprintf("Path of the worktree for %s", branch), defaultPath)
+	if err != nil {
+		return err
+	}
+	if err := runGitCommand(ctx, ioIn, ioErr, ioErr, "worktree", "add", path, branch); err != nil {
+		return fmt.Errorf("failed to add a worktree: %w", err)
+	}
+	return nil
+}
+
+// removeWorktrees removes worktrees, but a worktree with changes is removed only if a user confirms it
+func removeWorktrees(ctx context.Context, ioIn io.Reader, ioErr io.Writer, paths []string) error {
+	for _, path := range paths {
+		args := []string{"worktree", "remove", path}
+		status, err := runGitCommandOutput(ctx, "-C", path, "status", "--porcelain")
+		if err == nil && len(bytes.TrimSpace(status)) > 0 {
+			ok, err := confirm(ioIn, ioErr, fmt.Sprintf("%s has changes. Remove it anyway?", path))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			args = []string{"worktree", "remove", "--force", path}
+		}
+		if err := runGitCommand(ctx, ioIn, ioErr, ioErr, args...); err != nil {
+			return fmt.Errorf("failed to remove the worktree %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func listWorktrees(ctx context.Context) ([]worktree, error) {
+	out, err := runGitCommandOutput(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	worktrees := parseWorktreePorcelain(out)
+	if len(worktrees) == 0 {
+		return nil, errors.New("no worktree is found")
+	}
+	return worktrees, nil
+}
+
+// parseWorktreePorcelain parses the output of git worktree list --porcelain, where each worktree is separated by an empty line
+func parseWorktreePorcelain(out []byte) []worktree {
+	var worktrees []worktree
+	var current *worktree
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		text := scanner.Text()
+		if text == "" {
+			current = nil
+			continue
+		}
+		keyValue := strings.SplitN(text, " ", 2)
+		value := ""
+		if len(keyValue) == 2 {
+			value = keyValue[1]
+		}
+		if keyValue[0] == "worktree" {
+			worktrees = append(worktrees, worktree{path: value})
+			current = &worktrees[len(worktrees)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch keyValue[0] {
+		case "HEAD":
+			current.head = value
+		case "branch":
+			current.branch = strings.TrimPrefix(value, "refs/heads/")
+		case "detached":
+			current.detached = true
+		case "bare":
+			current.bare = true
+		case "locked":
+			current.locked = true
+		case "prunable":
+			current.prunable = true
+		}
+	}
+	return worktrees
+}
+
+func (w worktree) String() string {
+	branch := w.branch
+	if w.bare {
+		branch = "(bare)"
+	} else if w.detached {
+		branch = "(detached)"
+	}
+	head := w.head
+	if len(head) > shortHashLength {
+		head = head[:shortHashLength]
+	}
+	var states []string
+	if w.locked {
+		states = append(states, "locked")
+	}
+	if w.prunable {
+		states = append(states, "prunable")
+	}
+	return strings.Join([]string{w.path, branch, head, strings.Join(states, ",")}, "\t")
+}
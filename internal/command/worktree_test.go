@@ -0,0 +1,270 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWorktreeSubcommand(t *testing.T) {
+	assert.NotNil(t, NewWorktreeSubcommand())
+}
+
+func TestNewWorktreeCommand(t *testing.T) {
+	branchFzfOption := fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --no-multi", "git log --oneline --color -10 {}", defaultFzfBindOption)
+
+	testCases := []struct {
+		name     string
+		fzfQuery string
+		envVars  map[string]string
+		want     *worktreeCli
+		wantErr  error
+	}{
+		{
+			name:     "no options",
+			fzfQuery: "",
+			want: &worktreeCli{
+				fzfOption:       fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-a,alt-d,alt-p", "git -C {1} status --short --branch && git -C {1} log --oneline --color -10", defaultFzfBindOption),
+				branchFzfOption: branchFzfOption,
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "all options",
+			fzfQuery: "feature",
+			want: &worktreeCli{
+				fzfOption:       fmt.Sprintf("--multi --ansi --inline-info --layout reverse --preview '%s' --preview-window down:70%% --bind %s --delimiter '\\t' --expect alt-a,alt-d,alt-p --query feature", "git -C {1} status --short --branch && git -C {1} log --oneline --color -10", defaultFzfBindOption),
+				branchFzfOption: branchFzfOption,
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "GIT_FZF_FZF_OPTION includes invalid env",
+			fzfQuery: "",
+			envVars: map[string]string{
+				envNameFzfOption: "$UNKNOWN_ENV1, $UNKNOWN_ENV2",
+			},
+			want:    nil,
+			wantErr: fmt.Errorf("failed to get fzf option: %w", fmt.Errorf("%s has invalid environment variables: %s", envNameFzfOption, "UNKNOWN_ENV1,UNKNOWN_ENV2")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.envVars) > 0 {
+				defer func() {
+					for k := range tc.envVars {
+						require.NoError(t, os.Unsetenv(k))
+					}
+				}()
+				for k, v := range tc.envVars {
+					require.NoError(t, os.Setenv(k, v))
+				}
+			}
+			got, gotErr := newWorktreeCli(tc.fzfQuery)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func TestWorktreeCli_Run(t *testing.T) {
+	fzfOption := "--inline-info"
+	porcelain := "worktree /src/repo\nHEAD 268422956b889bf590d10ceb934204301aedab95\nbranch refs/heads/master\n\nworktree /src/repo-feature\nHEAD 368422956b889bf590d10ceb934204301aedab95\nbranch refs/heads/feature\n"
+	defaultWantErr := errors.New("want error")
+	exitErr := exec.ExitError{}
+
+	testCases := []struct {
+		name              string
+		ioIn              string
+		fzfOutputs        []string
+		fzfErr            error
+		gitOutputs        map[string]string
+		wantGitCommands   []string
+		sut               worktreeCli
+		wantErr           error
+		wantIO            string
+		wantIOErrContains string
+	}{
+		{
+			name: "path output",
+			fzfOutputs: []string{
+				"\n/src/repo-feature\tfeature\t3684229\t\n",
+			},
+			sut: worktreeCli{
+				fzfOption: fzfOption,
+			},
+			wantErr: nil,
+			wantIO:  "/src/repo-feature\n",
+		},
+		{
+			name: "prune and then output",
+			fzfOutputs: []string{
+				"alt-p\n/src/repo\tmaster\t2684229\t\n",
+				"\n/src/repo\tmaster\t2684229\t\n",
+			},
+			wantGitCommands: []string{
+				"worktree prune --verbose",
+			},
+			sut: worktreeCli{
+				fzfOption: fzfOption,
+			},
+			wantErr: nil,
+			wantIO:  "/src/repo\n",
+		},
+		{
+			name: "remove a dirty worktree after confirmation",
+			ioIn: "y\n",
+			fzfOutputs: []string{
+				"alt-d\n/src/repo-feature\tfeature\t3684229\t\n",
+				"\n/src/repo\tmaster\t2684229\t\n",
+			},
+			gitOutputs: map[string]string{
+				"-C /src/repo-feature status --porcelain": " M README.md\n",
+			},
+			wantGitCommands: []string{
+				"worktree remove --force /src/repo-feature",
+			},
+			sut: worktreeCli{
+				fzfOption: fzfOption,
+			},
+			wantErr:           nil,
+			wantIO:            "/src/repo\n",
+			wantIOErrContains: "/src/repo-feature has changes. Remove it anyway? (y/N): ",
+		},
+		{
+			name: "add a worktree for a branch",
+			ioIn: "\n",
+			fzfOutputs: []string{
+				"alt-a\n/src/repo\tmaster\t2684229\t\n",
+				"feature/new\n",
+				"\n/src/repo\tmaster\t2684229\t\n",
+			},
+			wantGitCommands: []string{
+				"worktree add /src/repo-feature-new feature/new",
+			},
+			sut: worktreeCli{
+				fzfOption:       fzfOption,
+				branchFzfOption: "--no-multi",
+			},
+			wantErr: nil,
+			wantIO:  "/src/repo\n",
+		},
+		{
+			name:   "command with fzf error",
+			fzfErr: defaultWantErr,
+			sut: worktreeCli{
+				fzfOption: fzfOption,
+			},
+			wantErr: defaultWantErr,
+			wantIO:  "",
+		},
+		{
+			name:   "command with fzf exit error (not 130)",
+			fzfErr: &exitErr,
+			sut: worktreeCli{
+				fzfOption: fzfOption,
+			},
+			wantErr: &exitErr,
+			wantIO:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fzfCount := 0
+			runCommandWithFzf = func(ctx context.Context, commandLine string, ioIn io.Reader, ioErr io.Writer) ([]byte, error) {
+				if tc.fzfErr != nil {
+					return nil, tc.fzfErr
+				}
+				require.True(t, fzfCount < len(tc.fzfOutputs))
+				in, err := ioutil.ReadAll(ioIn)
+				require.NoError(t, err)
+				assert.NotEmpty(t, in)
+				out := tc.fzfOutputs[fzfCount]
+				fzfCount++
+				return []byte(out), nil
+			}
+			runGitCommandOutput = func(ctx context.Context, args ...string) ([]byte, error) {
+				command := strings.Join(args, " ")
+				if command == "worktree list --porcelain" {
+					return []byte(porcelain), nil
+				}
+				if command == "for-each-ref --format=%(refname:short) refs/heads" {
+					return []byte("master\nfeature/new\n"), nil
+				}
+				return []byte(tc.gitOutputs[command]), nil
+			}
+			var gotGitCommands []string
+			runGitCommand = func(ctx context.Context, ioIn io.Reader, ioOut io.Writer, ioErr io.Writer, args ...string) error {
+				gotGitCommands = append(gotGitCommands, strings.Join(args, " "))
+				return nil
+			}
+
+			var gotIOOut bytes.Buffer
+			var gotIOErr bytes.Buffer
+			gotErr := tc.sut.Run(context.Background(), strings.NewReader(tc.ioIn), &gotIOOut, &gotIOErr)
+			assert.True(t, errors.Is(gotErr, tc.wantErr))
+			assert.Equal(t, tc.wantIO, gotIOOut.String())
+			assert.Equal(t, tc.wantGitCommands, gotGitCommands)
+			assert.Contains(t, gotIOErr.String(), tc.wantIOErrContains)
+		})
+	}
+}
+
+func TestParseWorktreePorcelain(t *testing.T) {
+	porcelain := `worktree /src/repo
+HEAD 268422956b889bf590d10ceb934204301aedab95
+branch refs/heads/master
+
+worktree /src/repo-detached
+HEAD 268422956b889bf590d10ceb934204301aedab95
+detached
+locked usb
+
+worktree /src/repo-feature
+HEAD 368422956b889bf590d10ceb934204301aedab95
+branch refs/heads/feature/a
+prunable gitdir file points to non-existent location
+`
+	got := parseWorktreePorcelain([]byte(porcelain))
+	assert.Equal(t, []worktree{
+		{
+			path:   "/src/repo",
+			head:   "268422956b889bf590d10ceb934204301aedab95",
+			branch: "master",
+		},
+		{
+			path:     "/src/repo-detached",
+			head:     "268422956b889bf590d10ceb934204301aedab95",
+			detached: true,
+			locked:   true,
+		},
+		{
+			path:     "/src/repo-feature",
+			head:     "368422956b889bf590d10ceb934204301aedab95",
+			branch:   "feature/a",
+			prunable: true,
+		},
+	}, got)
+
+	gotLines := make([]string, len(got))
+	for i, w := range got {
+		gotLines[i] = w.String()
+	}
+	assert.Equal(t, []string{
+		"/src/repo\tmaster\t2684229\t",
+		"/src/repo-detached\t(detached)\t2684229\tlocked",
+		"/src/repo-feature\tfeature/a\t3684229\tprunable",
+	}, gotLines)
+}